@@ -1,25 +1,95 @@
 package arbiter
 
 import (
+	"context"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
 	"github.com/btsomogyi/arbiter/internal"
 	"github.com/btsomogyi/arbiter/logging"
 	"github.com/btsomogyi/arbiter/telemetry"
 )
 
-type Supervisor struct {
-	*internal.Supervisor
+// Arbiter is implemented by both Supervisor and ShardedSupervisor, allowing consumers to
+// arbitrate requests keyed by K without regard to how the supervisor is structured.
+type Arbiter[K comparable] interface {
+	Process()
+	Terminate()
+	Shutdown(context.Context) error
+	WithWorker(context.Context, interfaces.Request[K], func(context.Context) error) error
+	WithWorkerOutcome(context.Context, interfaces.Request[K], func(context.Context) error) Outcome
+	Submit(context.Context, interfaces.Request[K], func(context.Context) error) *Ticket
+	TryWithWorker(context.Context, interfaces.Request[K], func(context.Context) error) error
+	Acquire(context.Context, interfaces.Request[K]) (*Lease[K], error)
+}
+
+// Outcome describes how a request submitted with WithWorkerOutcome completed.
+type Outcome = internal.Outcome
+
+// Phase identifies the stage of the worker protocol at which a request completed.
+type Phase = internal.Phase
+
+// Set of Phase values reported in an Outcome.
+const (
+	PhaseBegin = internal.PhaseBegin // ceased, or canceled, before proceeding.
+	PhaseWork  = internal.PhaseWork  // work function returned an error, so no Finalize.
+	PhaseEnd   = internal.PhaseEnd   // end message responded, after Finalize when work succeeded.
+)
+
+// Ticket tracks a request submitted asynchronously, providing its outcome once complete.
+type Ticket = internal.Ticket
+
+// Lease holds the keys of a request acquired by Acquire until released by Commit or Abort.  It is
+// declared as a struct embedding the Lease of package internal, rather than as an alias (which may
+// not be generic).
+type Lease[K comparable] struct {
+	*internal.Lease[K]
+}
+
+// newLease returns the Lease acquired by an Acquire call returning l and err.
+func newLease[K comparable](l *internal.Lease[K], err error) (*Lease[K], error) {
+	if err != nil {
+		return nil, err
+	}
+	return &Lease[K]{l}, nil
+}
+
+var _ Arbiter[int64] = (*Supervisor)(nil)
+var _ Arbiter[int64] = (*ShardedSupervisor[int64])(nil)
+
+// KeyedSupervisor arbitrates requests keyed by any comparable type K.
+type KeyedSupervisor[K comparable] struct {
+	*internal.Supervisor[K]
 }
 
+// Supervisor arbitrates requests keyed by int64, retained for consumers written
+// against the original (non-generic) Supervisor.
+type Supervisor = KeyedSupervisor[int64]
+
+// NewSupervisor returns an initialized int64 keyed Supervisor.
 func NewSupervisor(opts ...internal.SupervisorOption) (*Supervisor, error) {
-	s, err := internal.NewSupervisor(opts...)
+	return NewKeyedSupervisor[int64](opts...)
+}
+
+// NewKeyedSupervisor returns an initialized Supervisor for requests keyed by type K.
+func NewKeyedSupervisor[K comparable](opts ...internal.SupervisorOption) (*KeyedSupervisor[K], error) {
+	s, err := internal.NewSupervisor[K](opts...)
 	if err != nil {
 		return nil, err
 	}
-	return &Supervisor{
+	return &KeyedSupervisor[K]{
 		s,
 	}, nil
 }
 
+// Acquire arbitrates request r, returning a Lease once the request is permitted to proceed.  The
+// caller must Commit or Abort the Lease to release the key, otherwise the Lease is aborted once
+// ctx ends.  An unreleased Lease acquired with a context which never ends holds the key for as
+// long as it is referenced, since it is only aborted once garbage collected.
+func (s *KeyedSupervisor[K]) Acquire(ctx context.Context, r interfaces.Request[K]) (*Lease[K], error) {
+	return newLease(s.Supervisor.Acquire(ctx, r))
+}
+
 // SetInstrumentor sets the Instrumentor for the Supervisor.
 func SetInstrumentor(i telemetry.Instrumentor) internal.SupervisorOption {
 	return internal.SetInstrumentor(i)
@@ -30,6 +100,322 @@ func SetChannelDepth(d uint) internal.SupervisorOption {
 	return internal.SetChannelDepth(d)
 }
 
+// ShardedSupervisor distributes requests keyed by K across independent Supervisor shards by
+// key hash, each with its own processing loop.
+type ShardedSupervisor[K comparable] struct {
+	*internal.ShardedSupervisor[K]
+}
+
+// NewShardedSupervisor returns an initialized ShardedSupervisor for requests keyed by type K.
+func NewShardedSupervisor[K comparable](opts ...internal.SupervisorOption) (*ShardedSupervisor[K], error) {
+	s, err := internal.NewShardedSupervisor[K](opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &ShardedSupervisor[K]{
+		s,
+	}, nil
+}
+
+// Acquire arbitrates request r on the shard assigned to its key, returning a Lease once the
+// request is permitted to proceed (see KeyedSupervisor.Acquire).
+func (s *ShardedSupervisor[K]) Acquire(ctx context.Context, r interfaces.Request[K]) (*Lease[K], error) {
+	return newLease(s.ShardedSupervisor.Acquire(ctx, r))
+}
+
+// SetShardCount sets the number of shards used by a ShardedSupervisor (default GOMAXPROCS).
+func SetShardCount(n uint) internal.SupervisorOption {
+	return internal.SetShardCount(n)
+}
+
+// SetShardHash sets the function used by a ShardedSupervisor to hash request keys to shards.
+func SetShardHash[K comparable](h func(K) uint64) internal.SupervisorOption {
+	return internal.SetShardHash(h)
+}
+
+// WaitingPolicy determines how requests waiting for the same key are ordered, displaced and promoted.
+type WaitingPolicy = internal.WaitingPolicy
+
+// Set of WaitingPolicy values (see internal.WaitingPolicy for details).
+const (
+	NewestWins      = internal.NewestWins
+	FIFO            = internal.FIFO
+	SupersedesOrder = internal.SupersedesOrder
+)
+
+// Outcome errors classify why a request did not complete successfully, wrapping the underlying
+// consumer or context error in an OutcomeError so errors.Is matches either.
+var (
+	ErrInvalid         = internal.ErrInvalid
+	ErrSuperseded      = internal.ErrSuperseded
+	ErrDisplaced       = internal.ErrDisplaced
+	ErrCanceled        = internal.ErrCanceled
+	ErrWorkFailed      = internal.ErrWorkFailed
+	ErrFinalizeFailed  = internal.ErrFinalizeFailed
+	ErrLeaderFailed    = internal.ErrLeaderFailed
+	ErrKeyRange        = internal.ErrKeyRange
+	ErrDependencyCycle = internal.ErrDependencyCycle
+	ErrCrossShard      = internal.ErrCrossShard
+)
+
+// OutcomeError wraps the underlying error causing a request outcome with the outcome error.
+type OutcomeError = internal.OutcomeError
+
+// ErrShutdown indicates a request was ceased because the supervisor is shutting down (or has stopped).
+var ErrShutdown = internal.ErrShutdown
+
+// ErrWaitingQueueFull indicates a request was ceased because the waiting queue for its key was full.
+var ErrWaitingQueueFull = internal.ErrWaitingQueueFull
+
+// ErrPreempted indicates an in-flight request was canceled and not finalized, because a superseding
+// request arrived with preemption enabled.
+var ErrPreempted = internal.ErrPreempted
+
+// ErrLeaseReleased indicates a Lease was already released by Commit or Abort.
+var ErrLeaseReleased = internal.ErrLeaseReleased
+
+// PanicError is returned to the worker whose request method or work function panicked.  The
+// panic is recovered, logged, and counted by the Panics metric.
+type PanicError = internal.PanicError
+
+// ErrPending indicates the outcome of a submitted request is not yet available.
+var ErrPending = internal.ErrPending
+
+// ErrQueueFull indicates TryWithWorker could not send the request, because the supervisor queue
+// was full.
+var ErrQueueFull = internal.ErrQueueFull
+
+// ErrLoadShed indicates a request was shed by the configured load shedding policy.
+var ErrLoadShed = internal.ErrLoadShed
+
+// ErrRateLimited indicates a request was ceased because rate limit tokens were not available,
+// with the RateLimitReject policy.
+var ErrRateLimited = internal.ErrRateLimited
+
+// ErrExpired indicates a request was ceased because it was still waiting once its expiry or the
+// waiting TTL passed.
+var ErrExpired = internal.ErrExpired
+
+// ErrWorkStuck indicates the work of a request ran past the watchdog threshold, and its key was
+// released with SetWatchdogRelease.  The request is not finalized.
+var ErrWorkStuck = internal.ErrWorkStuck
+
+// RateLimitPolicy determines how requests activated without rate limit tokens available are handled.
+type RateLimitPolicy = internal.RateLimitPolicy
+
+// Set of RateLimitPolicy values (see internal.RateLimitPolicy for details).
+const (
+	RateLimitDelay  = internal.RateLimitDelay
+	RateLimitReject = internal.RateLimitReject
+)
+
+// Prioritized is optionally implemented by requests to set their priority, where higher priority
+// requests are handled ahead of lower priority requests.  Requests not implementing Prioritized
+// have priority zero.  Priority does not reorder requests waiting for a key under the default
+// NewestWins waiting policy.
+type Prioritized = interfaces.Prioritized
+
+// Weighted is optionally implemented by requests to set the number of rate limit tokens they
+// consume.  Requests not implementing Weighted cost one token.
+type Weighted = interfaces.Weighted
+
+// Expiring is optionally implemented by requests to set the time after which they are ceased with
+// ErrExpired if still waiting.
+type Expiring = interfaces.Expiring
+
+// Timed is optionally implemented by requests to set the maximum duration of their work,
+// overriding SetMaxWorkDuration.
+type Timed = interfaces.Timed
+
+// Mode is the access a request requires to its keys.
+type Mode = interfaces.Mode
+
+// Set of Mode values (see interfaces.Mode for details).
+const (
+	Exclusive = interfaces.Exclusive
+	Shared    = interfaces.Shared
+)
+
+// Moded is optionally implemented by requests to set the access they require to their keys.
+// Requests not implementing Moded are Exclusive.
+type Moded = interfaces.Moded
+
+// The generic optional request interfaces below are declared as interfaces embedding those of
+// package interfaces, rather than as aliases (which may not be generic), so are interchangeable
+// with them.
+
+// Merger is optionally implemented by requests which may be coalesced with a later request for the
+// same key while waiting.
+type Merger[K comparable] interface {
+	interfaces.Merger[K]
+}
+
+// MultiKeyRequest is optionally implemented by requests which must hold several keys at once.
+type MultiKeyRequest[K comparable] interface {
+	interfaces.MultiKeyRequest[K]
+}
+
+// HierarchicalRequest is optionally implemented by requests for resources forming a tree, holding
+// the other keys on their path as intentions.
+type HierarchicalRequest[K comparable] interface {
+	interfaces.HierarchicalRequest[K]
+}
+
+// RangeRequest is optionally implemented by requests reserving the contiguous range of keys
+// [lo, hi).  A ShardedSupervisor ceases range requests with ErrCrossShard.
+type RangeRequest[K comparable] interface {
+	interfaces.RangeRequest[K]
+}
+
+// DependentRequest is optionally implemented by requests which must only be applied once requests
+// for the keys they depend on have completed.
+type DependentRequest[K comparable] interface {
+	interfaces.DependentRequest[K]
+}
+
+// Conflicter is optionally implemented by requests to refine which requests for the same key they
+// conflict with under conflict scheduling.
+type Conflicter[K comparable] interface {
+	interfaces.Conflicter[K]
+}
+
+// SetWaitingDepth sets the maximum number of requests waiting per key.  Depth must be at least one.
+func SetWaitingDepth(d uint) internal.SupervisorOption {
+	return internal.SetWaitingDepth(d)
+}
+
+// SetWaitingPolicy sets the policy used to order, displace and promote waiting requests.
+func SetWaitingPolicy(p WaitingPolicy) internal.SupervisorOption {
+	return internal.SetWaitingPolicy(p)
+}
+
+// SetMaxProcessing sets the maximum number of in-flight processing entries across all keys
+// (per shard for a ShardedSupervisor).  Requests past the maximum wait in an admission queue,
+// remaining subject to supersession, until a processing entry completes.  Zero (the default)
+// places no limit on processing entries.
+func SetMaxProcessing(n uint) internal.SupervisorOption {
+	return internal.SetMaxProcessing(n)
+}
+
+// SetPreemption enables canceling the work context of an in-flight request when a superseding
+// request arrives for the same key.  The preempted request is not finalized and returns
+// ErrPreempted, and the superseding request is promoted once the preempted work function returns.
+func SetPreemption(enabled bool) internal.SupervisorOption {
+	return internal.SetPreemption(enabled)
+}
+
+// SetRevalidate enables checking Valid again when a waiting request is promoted, since the
+// in-flight request finalized ahead of it may have invalidated it.  Invalid waiting requests
+// are ceased with the error from Valid, and the next waiting request for the key is promoted.
+func SetRevalidate(enabled bool) internal.SupervisorOption {
+	return internal.SetRevalidate(enabled)
+}
+
+// SetExecutorPool sets the number of executors running Valid and Finalize off the supervisor
+// goroutine, so slow request calls for one key do not stall arbitration of other keys.  Zero
+// (the default) runs Valid and Finalize inline.
+func SetExecutorPool(n uint) internal.SupervisorOption {
+	return internal.SetExecutorPool(n)
+}
+
+// SetShedQueueDepth enables shedding requests with ErrLoadShed once the supervisor queue holds
+// at least d messages.  Zero (the default) disables depth based shedding.
+func SetShedQueueDepth(d uint) internal.SupervisorOption {
+	return internal.SetShedQueueDepth(d)
+}
+
+// SetShedQueueLatency enables CoDel style shedding of requests with ErrLoadShed, once queue
+// latency has remained above target for a full interval.  A zero target (the default) disables
+// latency based shedding.
+func SetShedQueueLatency(target, interval time.Duration) internal.SupervisorOption {
+	return internal.SetShedQueueLatency(target, interval)
+}
+
+// SetRateLimit sets a token bucket limiting the rate at which requests proceed across all keys
+// (per shard for a ShardedSupervisor), to rate tokens per second with bursts of up to burst
+// tokens.  Zero rate (the default) disables the limit.
+func SetRateLimit(rate, burst float64) internal.SupervisorOption {
+	return internal.SetRateLimit(rate, burst)
+}
+
+// SetWaitingTTL sets the maximum time a request may wait for its keys before it is ceased with
+// ErrExpired.  Requests implementing Expiring expire at the earlier of their expiry and the TTL.
+// Zero (the default) places no limit on waiting time.
+func SetWaitingTTL(d time.Duration) internal.SupervisorOption {
+	return internal.SetWaitingTTL(d)
+}
+
+// SetMaxWorkDuration sets the maximum duration of the work of a request, after which the context
+// passed to its work function ends.  Requests implementing Timed may override it.  Zero (the
+// default) places no limit on work duration.
+func SetMaxWorkDuration(d time.Duration) internal.SupervisorOption {
+	return internal.SetMaxWorkDuration(d)
+}
+
+// SetWatchdog enables a watchdog reporting requests whose work has run longer than threshold, so
+// work which ignores its context and holds its keys indefinitely is detected.  Each stuck request
+// is logged, counted in the StuckKeys gauge, and reported once to stuck, if provided.
+func SetWatchdog[K comparable](threshold time.Duration, stuck func(key K, running time.Duration)) internal.SupervisorOption {
+	return internal.SetWatchdog(threshold, stuck)
+}
+
+// SetWatchdogRelease enables the watchdog to release the keys of stuck requests, canceling their
+// work context, so waiting requests proceed.  Released requests are not finalized and return
+// ErrWorkStuck.
+func SetWatchdogRelease(enabled bool) internal.SupervisorOption {
+	return internal.SetWatchdogRelease(enabled)
+}
+
+// SetKeyRateLimit sets a token bucket per key, limiting the rate at which requests for each key
+// proceed to rate tokens per second with bursts of up to burst tokens.  Zero rate (the default)
+// disables per key limits.
+func SetKeyRateLimit(rate, burst float64) internal.SupervisorOption {
+	return internal.SetKeyRateLimit(rate, burst)
+}
+
+// SetRateLimitPolicy sets the policy for requests activated without rate limit tokens available.
+func SetRateLimitPolicy(p RateLimitPolicy) internal.SupervisorOption {
+	return internal.SetRateLimitPolicy(p)
+}
+
+// SetPriorityLevels enables a multi-level queue in front of the supervisor loop, so requests
+// implementing Prioritized are processed ahead of queued lower priority requests.  Priorities are
+// clamped to levels 0 through n-1.  Zero (the default) processes requests in arrival order.
+// Requests waiting for a key are ordered by the waiting policy instead (see SetWaitingPolicy).
+func SetPriorityLevels(n uint) internal.SupervisorOption {
+	return internal.SetPriorityLevels(n)
+}
+
+// SetPriorityAging raises the effective priority of a queued request by one level for each
+// interval d it has been queued, so lower priority requests are not starved.  Zero (the default)
+// disables aging.
+func SetPriorityAging(d time.Duration) internal.SupervisorOption {
+	return internal.SetPriorityAging(d)
+}
+
+// SetFollowMode enables following: a request ceased as redundant attaches to the in-flight or
+// waiting request superseding it, and receives that request's outcome once it completes rather
+// than an immediate ErrSuperseded or ErrDisplaced.  Followers of a failed request receive
+// ErrLeaderFailed.
+func SetFollowMode(enabled bool) internal.SupervisorOption {
+	return internal.SetFollowMode(enabled)
+}
+
+// SetConflictScheduling enables conflict scheduling: requests for the same key are serialized only
+// where they conflict, as reported by interfaces.Conflicter, so non-conflicting requests proceed
+// concurrently.  Requests not implementing interfaces.Conflicter conflict with every request for
+// their keys.
+func SetConflictScheduling(enabled bool) internal.SupervisorOption {
+	return internal.SetConflictScheduling(enabled)
+}
+
+// SetKeyOrder sets the order of request keys used to arbitrate key ranges (see
+// interfaces.RangeRequest).  Keys of integer, floating point and string kinds are ordered
+// naturally if not provided.
+func SetKeyOrder[K comparable](less func(a, b K) bool) internal.SupervisorOption {
+	return internal.SetKeyOrder(less)
+}
+
 // SetLogger provides a compatible structured logger for emitting log messages.
 // If not provided, a no-op logger is created during supervisor initialization.
 func SetLogger(l logging.Logger) internal.SupervisorOption {
@@ -35,6 +35,7 @@ func init() {
 type VersionerRequest struct {
 	id       int64
 	version  int64
+	mode     interfaces.Mode
 	valid    func() error
 	finalize func() error
 }
@@ -49,9 +50,15 @@ func (v *VersionerRequest) GetValue() int64 {
 	return v.version
 }
 
+// Mode returns the access the request requires to the element, so reads may proceed
+// concurrently while updates are serialized.
+func (v *VersionerRequest) Mode() interfaces.Mode {
+	return v.mode
+}
+
 // Supersedes determines whether a given request has a higher version number
 // than the request being compared.
-func (v *VersionerRequest) Supersedes(o interfaces.Request) error {
+func (v *VersionerRequest) Supersedes(o interfaces.Int64Request) error {
 	otherVersionReq, ok := o.(*VersionerRequest)
 	if !ok {
 		st := status.New(codes.Internal, "failed to cast request as 'VersionerRequest'")
@@ -102,4 +109,5 @@ func (v *VersionerRequest) Finalize() error {
 	return v.finalize()
 }
 
-var _ interfaces.Request = (*VersionerRequest)(nil)
+var _ interfaces.Int64Request = (*VersionerRequest)(nil)
+var _ interfaces.Moded = (*VersionerRequest)(nil)
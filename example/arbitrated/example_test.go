@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/btsomogyi/arbiter"
+	"github.com/btsomogyi/arbiter/example"
 	"math/rand"
 	"net"
 	"testing"
@@ -27,6 +28,26 @@ var (
 )
 
 func Benchmark_randomRequests(b *testing.B) {
+	runRandomRequests(b, func() (arbiter.Arbiter[int64], error) {
+		return arbiter.NewSupervisor(arbiter.SetLogger(logging.NewNoopLogger()))
+	}, func() example.Store {
+		return NewSimpleStore()
+	})
+}
+
+// Benchmark_randomRequestsSharded runs the same request sequences as Benchmark_randomRequests,
+// using a ShardedSupervisor (one shard per GOMAXPROCS) in place of a single Supervisor.
+func Benchmark_randomRequestsSharded(b *testing.B) {
+	runRandomRequests(b, func() (arbiter.Arbiter[int64], error) {
+		return arbiter.NewShardedSupervisor[int64](arbiter.SetLogger(logging.NewNoopLogger()))
+	}, func() example.Store {
+		return NewSyncStore()
+	})
+}
+
+// runRandomRequests benchmarks a Versioner GRPC server using the arbiter returned by newArbiter
+// and store returned by newStore, across a range of request counts and client concurrency.
+func runRandomRequests(b *testing.B, newArbiter func() (arbiter.Arbiter[int64], error), newStore func() example.Store) {
 	benchmarks := []struct {
 		Requests    int
 		Concurrency int
@@ -98,11 +119,9 @@ func Benchmark_randomRequests(b *testing.B) {
 		b.Run(fmt.Sprintf("%d reqs @ %d", bm.Requests, bm.Concurrency), func(b *testing.B) {
 			for n := 0; n < b.N; n++ {
 				b.StopTimer()
-				logger := logging.NewNoopLogger()
 
 				// Start GRPC Server
-				supervisorOptions := arbiter.SetLogger(logger)
-				supervisor, err := arbiter.NewSupervisor(supervisorOptions)
+				supervisor, err := newArbiter()
 				if err != nil {
 					b.Fatal(err)
 				}
@@ -119,7 +138,7 @@ func Benchmark_randomRequests(b *testing.B) {
 					time.Sleep(defaultWorkTime)
 					return nil
 				})
-				pb.RegisterVersionerServer(grpcServer, NewVersioner(supervisor, option))
+				pb.RegisterVersionerServer(grpcServer, NewVersioner(supervisor, option, SetStore(newStore())))
 				go grpcServer.Serve(lis)
 
 				// Setup client
@@ -1,10 +1,13 @@
 package arbitrated
 
 import (
+	"sync"
+
 	"github.com/btsomogyi/arbiter/example"
 )
 
 var _ example.Store = (*SimpleStore)(nil)
+var _ example.Store = (*SyncStore)(nil)
 
 // SimpleStore is a stand-in for any data backend; Element key / version map.
 type SimpleStore map[int64]int64
@@ -41,3 +44,39 @@ func (db SimpleStore) Delete(key int64) error {
 	delete(db, key)
 	return nil
 }
+
+// SyncStore is a SimpleStore guarded by a read/write mutex.  A single Supervisor serializes all
+// Valid and Finalize calls, so needs no locking, but the shards of a ShardedSupervisor call them
+// concurrently (for different keys) so require a store safe for concurrent use.
+type SyncStore struct {
+	store SimpleStore
+	mtx   sync.RWMutex
+}
+
+// NewSyncStore creates an initialized SyncStore.
+func NewSyncStore() *SyncStore {
+	return &SyncStore{
+		store: NewSimpleStore(),
+	}
+}
+
+// Update sets the installed version for the passed key once the write lock is obtained.
+func (s *SyncStore) Update(key int64, version int64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.store.Update(key, version)
+}
+
+// Get returns the current version of a given Element key once the read lock is obtained.
+func (s *SyncStore) Get(key int64) (*int64, error) {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.store.Get(key)
+}
+
+// Delete removes an entry once the write lock is obtained.
+func (s *SyncStore) Delete(key int64) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.store.Delete(key)
+}
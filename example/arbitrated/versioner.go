@@ -21,13 +21,14 @@ var _ examplepb.VersionerServer = (*Versioner)(nil)
 type Versioner struct {
 	Elements   example.Store
 	workFunc   func() error
-	Supervisor *arbiter.Supervisor
+	Supervisor arbiter.Arbiter[int64]
 	examplepb.UnimplementedVersionerServer
 }
 
 // config contains the adjustable configuraiton of the Versioner.
 type config struct {
 	workFunc func() error
+	store    example.Store
 }
 
 // A VersionerOption is a function that modifies the behavior of a Versioner.
@@ -41,23 +42,44 @@ func SetWorkFunc(wf func() error) VersionerOption {
 	}
 }
 
+// SetStore sets the backing store for the Versioner.  The store must be safe for concurrent
+// use if the Versioner is arbitrated by a ShardedSupervisor, as each shard finalizes requests
+// concurrently.  If not provided, an (unsynchronized) SimpleStore is used.
+func SetStore(store example.Store) VersionerOption {
+	return func(c *config) error {
+		c.store = store
+		return nil
+	}
+}
+
 // NewVersioner constructs and returns an empty Versioner.
-func NewVersioner(s *arbiter.Supervisor, opts ...VersionerOption) *Versioner {
+func NewVersioner(s arbiter.Arbiter[int64], opts ...VersionerOption) *Versioner {
+	var cfg config
+	for _, opt := range opts {
+		// Versioner options do not return errors.
+		_ = opt(&cfg)
+	}
 	v := &Versioner{
-		Elements:   NewSimpleStore(),
+		Elements:   cfg.store,
+		workFunc:   cfg.workFunc,
 		Supervisor: s,
 	}
+	if v.Elements == nil {
+		v.Elements = NewSimpleStore()
+	}
 	return v
 }
 
 // GetVersion returns the current Element version for the requested key.  Utilizes Arbiter Supervisor
-// to ensure serialized access to underlying data (ElementMap).
+// in shared mode, so reads of the underlying data (ElementMap) proceed concurrently, but never
+// alongside an update.
 func (v *Versioner) GetVersion(ctx context.Context, req *examplepb.GetVersionRequest) (*examplepb.VersionResponse, error) {
 	key := req.GetKey().Id
 	var version int64
 
 	request := &VersionerRequest{
-		id: key,
+		id:   key,
+		mode: arbiter.Shared,
 		valid: func() error {
 			// Only checking if key is found, ignoring returned value
 			_, err := v.Elements.Get(key)
@@ -1,15 +1,132 @@
 package interfaces
 
-// Request is interface used as key to waiting and processing maps.
-// Note that GetKey() must return a hashable Value (not enforcable in Go1). TODO [BTS] Go2 Constraints
-type Request interface {
-	GetKey() int64
+import "time"
+
+// Request is interface used as key to waiting and processing maps. K is the type of
+// the key returned by GetKey(), and may be any comparable type (integers, strings,
+// UUID arrays, or composite structs of comparable fields).
+type Request[K comparable] interface {
+	GetKey() K
 	// Valid checks to ensure a request being taken from queue is still valid to process.  Return an error if
 	// the request is no longer valid.
 	Valid() error
 	// Supersedes checks if receiver request represents a newer request than provided request.
 	// Return an error if receiver request is superseded, nil error if it supersedes the provided request.
-	Supersedes(Request) error
+	Supersedes(Request[K]) error
 	// Finalize all concluding work/persistence/cleanup once request is processed.
 	Finalize() error
 }
+
+// Weighted is optionally implemented by requests to set the number of rate limit tokens they
+// consume.  Requests not implementing Weighted cost one token.
+type Weighted interface {
+	Cost() float64
+}
+
+// Prioritized is optionally implemented by requests to set their priority, where higher priority
+// requests are handled ahead of lower priority requests.  Requests not implementing Prioritized
+// have priority zero.  Priority orders requests queued for the supervisor, and those waiting for a
+// key under the FIFO and SupersedesOrder waiting policies, but not under the default NewestWins
+// policy, where each waiting request must supersede the last and is promoted in arrival order.
+type Prioritized interface {
+	Priority() int
+}
+
+// Expiring is optionally implemented by requests whose data goes stale, to set the time after
+// which they must no longer be applied.  A request still waiting once Expires passes is ceased
+// with ErrExpired.  Requests returning the zero time do not expire.
+type Expiring interface {
+	Expires() time.Time
+}
+
+// Timed is optionally implemented by requests to set the maximum duration of their work,
+// overriding the maximum work duration of the supervisor.  The context passed to the work
+// function ends once WorkTimeout passes.  Requests returning zero or less use the supervisor
+// default.
+type Timed interface {
+	WorkTimeout() time.Duration
+}
+
+// Mode is the access a request requires to its keys.
+type Mode int
+
+const (
+	// Exclusive requests hold their keys alone, and supersede one another.
+	Exclusive Mode = iota
+	// Shared requests hold their keys concurrently with other shared requests, and are never
+	// superseded.
+	Shared
+)
+
+// Moded is optionally implemented by requests to set the access they require to their keys, such
+// as a read which may proceed alongside other reads.  Requests not implementing Moded are
+// Exclusive.
+type Moded interface {
+	Mode() Mode
+}
+
+// MultiKeyRequest is optionally implemented by requests which must hold several keys at once,
+// such as an operation moving a resource between parents.  GetKeys returns the keys held in
+// addition to GetKey, which continues to identify the request in logs and metrics.  With a
+// sharded supervisor, every key must hash to the shard of GetKey, otherwise the request is
+// ceased with ErrCrossShard.
+type MultiKeyRequest[K comparable] interface {
+	Request[K]
+	GetKeys() []K
+}
+
+// HierarchicalRequest is optionally implemented by requests for resources forming a tree, such as
+// tenant, cluster and node.  GetPath returns the key path from the root of the tree to the
+// resource, such as the tenant, cluster and node keys of a node.  The request operates on GetKey
+// (and any keys of MultiKeyRequest), and holds the other keys on its path as intentions: an
+// operation on an ancestor excludes operations on its descendants, and the reverse, while
+// operations on different descendants proceed concurrently.  With a sharded supervisor, every
+// key on the path must hash to the shard of GetKey, otherwise the request is ceased with
+// ErrCrossShard.
+type HierarchicalRequest[K comparable] interface {
+	Request[K]
+	GetPath() []K
+}
+
+// RangeRequest is optionally implemented by requests covering a contiguous range of keys, such as
+// a shard split or bulk migration.  GetRange returns the range [lo, hi) reserved by the request,
+// while GetKey continues to identify the request in logs and metrics.  Requests for keys within a
+// reserved range wait until it is released, and supersession applies only between requests
+// reserving identical ranges.  Keys are ordered by the key order of the supervisor.  Range requests
+// cannot be arbitrated by a sharded supervisor, which ceases them with ErrCrossShard.
+type RangeRequest[K comparable] interface {
+	Request[K]
+	GetRange() (lo, hi K)
+}
+
+// DependentRequest is optionally implemented by requests which must only be applied once changes
+// to other keys have completed, such as child configuration which must land after its parent.
+// DependsOn returns the keys the request depends on.  The request is held until no request for
+// any of those keys is processing or waiting, and is then arbitrated for its own keys as usual.
+// Requests whose dependencies form a cycle are rejected.  With a sharded supervisor, every key
+// depended on must hash to the shard of GetKey, otherwise the request is ceased with
+// ErrCrossShard, as cycles are only detected among the requests of one shard.
+type DependentRequest[K comparable] interface {
+	Request[K]
+	DependsOn() []K
+}
+
+// Merger is optionally implemented by requests which may be coalesced with a later request for
+// the same key while waiting.  Merge returns the request combining the receiver with the later
+// request, or an error if they cannot be combined.  The merged request must have the same key.
+type Merger[K comparable] interface {
+	Merge(Request[K]) (Request[K], error)
+}
+
+// Conflicter is optionally implemented by requests to refine which requests for the same key they
+// conflict with, such as updates to different fields of one record, which may proceed
+// concurrently.  ConflictsWith is only consulted with conflict scheduling enabled, and only for
+// requests holding a key in common.  Requests conflict unless each reports no conflict with the
+// other.
+type Conflicter[K comparable] interface {
+	ConflictsWith(Request[K]) bool
+}
+
+// Int64Request is the int64 keyed Request, retained for consumers written against
+// the original (non-generic) Request interface.
+type Int64Request = Request[int64]
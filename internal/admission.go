@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/btsomogyi/arbiter/logging"
+)
+
+// admissionEntry is a message which holds its key in the processing map, but is awaiting a free
+// processing slot before being sent proceedSignal.
+type admissionEntry[K comparable] struct {
+	m      message[K]
+	queued time.Time
+}
+
+// atCapacity reports whether the number of in-flight processing entries has reached the maximum.
+func (s *Supervisor[K]) atCapacity() bool {
+	return s.maxProcessing > 0 && s.running >= s.maxProcessing
+}
+
+// queueAdmission reserves the message key in the processing map and appends the message to the
+// admission queue, to be admitted in arrival order as processing slots free.
+func (s *Supervisor[K]) queueAdmission(m message[K]) {
+	s.processing.add(m)
+	m.setStatus(msAdmission)
+	s.admission = append(s.admission, admissionEntry[K]{m: m, queued: time.Now()})
+	s.metrics.IncAdmissionQueueDepth()
+}
+
+// replaceAdmission ceases the old message awaiting admission, and places the superseding message
+// in its processing map entry and admission queue position.
+func (s *Supervisor[K]) replaceAdmission(old, m message[K]) {
+	for i := range s.admission {
+		if s.admission[i].m.same(old) {
+			s.admission[i] = admissionEntry[K]{m: m, queued: time.Now()}
+			break
+		}
+	}
+	s.processing.add(m)
+	m.setStatus(msAdmission)
+
+	old.unsetStatus(msAdmission)
+	err := s.callSupersedes(old.request(), m.request())
+	if s.follow(old, m, err) {
+		return
+	}
+	old.setStatus(msCease)
+	s.pushMessageMetrics(old)
+	old.respond(beginState, ceaseSignal, wrapOutcome(ErrDisplaced, err))
+}
+
+// removeAdmission removes the message from the admission queue, if present.
+func (s *Supervisor[K]) removeAdmission(m message[K]) {
+	for i := range s.admission {
+		if s.admission[i].m.same(m) {
+			s.admission = append(s.admission[:i], s.admission[i+1:]...)
+			s.metrics.DecAdmissionQueueDepth()
+			return
+		}
+	}
+}
+
+// admitNext activates messages from the head of the admission queue while processing slots are
+// available.  Requests are validated again on admission, as they may have become invalid while
+// queued, in which case they are ceased and the next waiting message for the key is promoted.
+// With rate limits, messages are admitted in arrival order as their tokens become available.
+func (s *Supervisor[K]) admitNext() {
+	now := time.Now()
+	for !s.atCapacity() {
+		i, wait := s.nextAdmissible(now)
+		if i < 0 {
+			if wait > 0 {
+				s.limiter.schedule(now, wait)
+			}
+			return
+		}
+		entry := s.admission[i]
+		s.admission = append(s.admission[:i], s.admission[i+1:]...)
+		s.metrics.DecAdmissionQueueDepth()
+		s.metrics.AdmissionWait(timeElapsedInSeconds(entry.queued))
+
+		m := entry.m
+		m.unsetStatus(msAdmission)
+		if !s.revalidateHeld(m, admitOp) {
+			s.logger.Debug("Request invalidated awaiting admission", []logging.LogTuple{
+				{Field: "key", Value: m.keyset().key},
+			})
+			s.processing.remove(m)
+			s.releaseMessage(m)
+		}
+	}
+}
+
+// drainAdmission ceases all messages awaiting admission with err, releasing their keys from
+// the processing map.
+func (s *Supervisor[K]) drainAdmission(err error) {
+	for _, entry := range s.admission {
+		m := entry.m
+		s.metrics.DecAdmissionQueueDepth()
+		s.processing.remove(m)
+		m.unsetStatus(msAdmission)
+		m.setStatus(msCease)
+		s.pushMessageMetrics(m)
+		m.respond(beginState, ceaseSignal, err)
+	}
+	s.admission = nil
+}
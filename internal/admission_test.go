@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// admissionStep is a single message sent directly to the Supervisor under test, followed by
+// checks of the processing and admission gauges.
+type admissionStep struct {
+	req            string // named request the step pertains to
+	end            bool   // send end message (failure unless succeed), rather than begin message
+	succeed        bool   // send end message with successSignal
+	invalidate     bool   // make the request invalid in the store, rather than send a message
+	shutdown       bool   // begin supervisor shutdown, rather than send a message
+	wantProcessing int64
+	wantAdmission  int64
+}
+
+func Test_SupervisorMaxProcessing(t *testing.T) {
+	tests := map[string]struct {
+		max       uint
+		steps     []admissionStep
+		wantSigs  map[string][]signal
+		wantErr   map[string]error
+		wantAdmit int64 // number of admission wait observations
+	}{
+		"admitted in arrival order": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record3version30", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version20", wantProcessing: 1, wantAdmission: 2},
+				{req: "record2version10", end: true, succeed: true, wantProcessing: 1, wantAdmission: 1},
+				{req: "record3version30", end: true, succeed: true, wantProcessing: 1},
+				{req: "record1version20", end: true, succeed: true},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal, successSignal},
+				"record3version30": {proceedSignal, successSignal},
+				"record1version20": {proceedSignal, successSignal},
+			},
+			wantAdmit: 2,
+		},
+		"unlimited by default": {
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record3version30", wantProcessing: 2},
+				{req: "record1version20", wantProcessing: 3},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal},
+				"record3version30": {proceedSignal},
+				"record1version20": {proceedSignal},
+			},
+		},
+		"superseding request replaces queued request": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record1version9", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version10", wantProcessing: 1, wantAdmission: 1},
+				{req: "record2version10", end: true, succeed: true, wantProcessing: 1},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal, successSignal},
+				"record1version9":  {ceaseSignal},
+				"record1version10": {proceedSignal},
+			},
+			wantErr: map[string]error{
+				"record1version9": ErrSupersededRequest,
+			},
+			wantAdmit: 1,
+		},
+		"superseded request ceased while queued": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record1version10", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version9", wantProcessing: 1, wantAdmission: 1},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal},
+				"record1version10": nil,
+				"record1version9":  {ceaseSignal},
+			},
+			wantErr: map[string]error{
+				"record1version9": ErrSupersededRequest,
+			},
+		},
+		"invalidated while queued": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record1version9", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version20", invalidate: true, wantProcessing: 1, wantAdmission: 1},
+				{req: "record2version10", end: true, succeed: true},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal, successSignal},
+				"record1version9":  {ceaseSignal},
+			},
+			wantErr: map[string]error{
+				"record1version9": ErrInvalidRequest,
+			},
+			wantAdmit: 1,
+		},
+		"canceled while queued": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record1version9", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version9", end: true, wantProcessing: 1},
+				{req: "record2version10", end: true, succeed: true},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal, successSignal},
+				"record1version9":  {failureSignal},
+			},
+		},
+		"promoted waiting request queues behind admission": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record1version9", wantProcessing: 1},
+				{req: "record1version10", wantProcessing: 1},
+				{req: "record2version10", wantProcessing: 1, wantAdmission: 1},
+				{req: "record1version9", end: true, succeed: true, wantProcessing: 1, wantAdmission: 1},
+				{req: "record2version10", end: true, succeed: true, wantProcessing: 1},
+			},
+			wantSigs: map[string][]signal{
+				"record1version9":  {proceedSignal, successSignal},
+				"record1version10": {proceedSignal},
+				"record2version10": {proceedSignal, successSignal},
+			},
+			wantAdmit: 2,
+		},
+		"shutdown ceases queued requests": {
+			max: 1,
+			steps: []admissionStep{
+				{req: "record2version10", wantProcessing: 1},
+				{req: "record1version9", wantProcessing: 1, wantAdmission: 1},
+				{shutdown: true, wantProcessing: 1},
+			},
+			wantSigs: map[string][]signal{
+				"record2version10": {proceedSignal},
+				"record1version9":  {ceaseSignal},
+			},
+			wantErr: map[string]error{
+				"record1version9": ErrShutdown,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			li := at.NewLocalInstrumentor()
+			cfg, err := newConfig(SetInstrumentor(li), SetMaxProcessing(tc.max))
+			if err != nil {
+				t.Fatalf("failed to configure supervisor: %v", err)
+			}
+			s := &Supervisor[int64]{}
+			s.init(cfg)
+
+			var db mtxMap
+			begins := make(map[string]*beginMessage[int64])
+			responses := make(map[string]*[]response)
+			for i, step := range tc.steps {
+				switch {
+				case step.shutdown:
+					s.beginShutdown()
+				case step.invalidate:
+					db.set(requestDefs[step.req].key, requestDefs[step.req].value)
+				case step.end:
+					sig := failureSignal
+					if step.succeed {
+						sig = successSignal
+					}
+					s.processEnd(newTestEnd(begins[step.req], sig))
+				default:
+					r := requestDefs[step.req]
+					setupTestItem(&r, &db)
+					rec := &[]response{}
+					responses[step.req] = rec
+					begins[step.req] = newTestBegin[int64](&r, rec)
+					s.processBegin(begins[step.req])
+				}
+				gauges := li.SnapMetrics().Gauges
+				if got := gauges[at.ProcessingMapDepth]; got != step.wantProcessing {
+					t.Errorf("step %d: expected processing depth %d, got %d", i, step.wantProcessing, got)
+				}
+				if got := gauges[at.AdmissionQueueDepth]; got != step.wantAdmission {
+					t.Errorf("step %d: expected admission depth %d, got %d", i, step.wantAdmission, got)
+				}
+			}
+
+			// Keys are released once every processing entry has ended.
+			if li.SnapMetrics().Gauges[at.ProcessingMapDepth] == 0 && s.processing.length() != 0 {
+				t.Errorf("expected no keys held, got %v", s.processing.dump())
+			}
+
+			for name, want := range tc.wantSigs {
+				got := *responses[name]
+				if len(got) != len(want) {
+					t.Errorf("request %s expected responses %v, got %v", name, want, got)
+					continue
+				}
+				for i := range want {
+					if got[i].sig != want[i] {
+						t.Errorf("request %s response %d expected %s, got %s", name, i, want[i], got[i].sig)
+					}
+				}
+				if len(got) > 0 && !errors.Is(got[len(got)-1].err, tc.wantErr[name]) {
+					t.Errorf("request %s expected error %v, got %v", name, tc.wantErr[name], got[len(got)-1].err)
+				}
+			}
+			if got := li.SnapMetrics().HistogramSummaries()[at.AdmissionWait]; got != tc.wantAdmit {
+				t.Errorf("expected %d admission wait observations, got %d", tc.wantAdmit, got)
+			}
+		})
+	}
+}
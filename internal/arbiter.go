@@ -46,9 +46,11 @@ func (s signal) String() string {
 // response is the message sent back to worker from Arbiter supervisor to worker. state indicates
 // whether response to begin or end message, and signal and Error provide response to worker.
 type response struct {
-	state state
-	sig   signal
-	err   error
+	state  state
+	sig    signal
+	err    error
+	status messageStatus
+	leader interface{} // request followed by the worker (see SetFollowMode).
 }
 
 // String is stringer for response members.
@@ -60,14 +62,15 @@ func (r *response) String() string {
 // panic-free responses to arbiter worker over an enclosed channel. state indicates
 // begin/end type, and signal provides indication to worker about request status
 // (proceedSignal/ceaseSignal for beginState responses, successSignal/failureSignal
-// for end responses).
-type responseFunc func(state, signal, error)
+// for end responses).  The message status is passed to report the outcome to the worker.
+type responseFunc func(state, signal, error, messageStatus)
 
-type message interface {
-	request() interfaces.Request
+type message[K comparable] interface {
+	request() interfaces.Request[K]
+	keyset() *keyset[K]
 	respond(state, signal, error)
-	signature() *worker
-	same(message) bool
+	signature() *worker[K]
+	same(message[K]) bool
 	setLatency()
 	getLatency() float64
 	setStatus(messageStatus)
@@ -75,102 +78,128 @@ type message interface {
 	getStatus() messageStatus
 }
 
-var _ message = (*beginMessage)(nil)
+var _ message[int64] = (*beginMessage[int64])(nil)
 
-type beginMessage struct {
-	req          interfaces.Request
+type beginMessage[K comparable] struct {
+	req          interfaces.Request[K]
 	responseFunc responseFunc
 	timestamp    time.Time
 	latency      float64
-	workerSig    *worker
+	workerSig    *worker[K]
 	status       messageStatus
+	seq          uint64          // arrival order at the supervisor, assigned when first enqueued.
+	mode         interfaces.Mode // access required to the keys, assigned when first enqueued.
+	expires      time.Time       // waiting deadline, assigned when first waitlisted.
+	expiry       *expiryEntry[K] // scheduled expiry while waiting.
+	proceeded    time.Time       // time the request proceeded, watched by the watchdog.
+	stuck        bool            // work ran past the watchdog threshold, and was reported.
 }
 
-func (m *beginMessage) request() interfaces.Request {
+func (m *beginMessage[K]) request() interfaces.Request[K] {
+	if m.workerSig != nil {
+		return m.workerSig.current()
+	}
 	return m.req
 }
 
-func (m *beginMessage) respond(state state, signal signal, err error) {
-	m.responseFunc(state, signal, err)
+func (m *beginMessage[K]) keyset() *keyset[K] {
+	if m.workerSig != nil {
+		return m.workerSig.keyset()
+	}
+	return newKeyset(m.req)
 }
 
-func (m *beginMessage) signature() *worker {
+func (m *beginMessage[K]) respond(state state, signal signal, err error) {
+	m.responseFunc(state, signal, err, m.status)
+}
+
+func (m *beginMessage[K]) signature() *worker[K] {
 	return m.workerSig
 }
 
-func (m *beginMessage) setLatency() {
+func (m *beginMessage[K]) setLatency() {
 	m.latency = timeElapsedInSeconds(m.timestamp)
 }
 
-func (m *beginMessage) getLatency() float64 {
+func (m *beginMessage[K]) getLatency() float64 {
 	return m.latency
 }
 
-func (m *beginMessage) setStatus(ms messageStatus) {
+func (m *beginMessage[K]) setStatus(ms messageStatus) {
 	m.status.addStatus(ms)
 }
 
-func (m *beginMessage) unsetStatus(ms messageStatus) {
+func (m *beginMessage[K]) unsetStatus(ms messageStatus) {
 	m.status.removeStatus(ms)
 }
 
-func (m *beginMessage) getStatus() messageStatus {
+func (m *beginMessage[K]) getStatus() messageStatus {
 	return m.status
 }
 
 // same identifies one message 'm' as having the same identity with an'other' message 'o',
 // having identical requests and being from the same worker (identical worker signatures).
-func (m *beginMessage) same(o message) bool {
+func (m *beginMessage[K]) same(o message[K]) bool {
 	return m.signature() == o.signature() && m.request() == o.request()
 }
 
-var _ message = (*endMessage)(nil)
+var _ message[int64] = (*endMessage[int64])(nil)
 
-type endMessage struct {
-	req          interfaces.Request
+type endMessage[K comparable] struct {
+	req          interfaces.Request[K]
 	responseFunc responseFunc
 	signal       signal
 	timestamp    time.Time
 	latency      float64
-	workerSig    *worker
+	workerSig    *worker[K]
 	status       messageStatus
 }
 
-func (m *endMessage) request() interfaces.Request {
+func (m *endMessage[K]) request() interfaces.Request[K] {
+	if m.workerSig != nil {
+		return m.workerSig.current()
+	}
 	return m.req
 }
 
-func (m *endMessage) respond(state state, signal signal, err error) {
-	m.responseFunc(state, signal, err)
+func (m *endMessage[K]) keyset() *keyset[K] {
+	if m.workerSig != nil {
+		return m.workerSig.keyset()
+	}
+	return newKeyset(m.req)
+}
+
+func (m *endMessage[K]) respond(state state, signal signal, err error) {
+	m.responseFunc(state, signal, err, m.status)
 }
 
-func (m *endMessage) signature() *worker {
+func (m *endMessage[K]) signature() *worker[K] {
 	return m.workerSig
 }
 
-func (m *endMessage) setLatency() {
+func (m *endMessage[K]) setLatency() {
 	m.latency = timeElapsedInSeconds(m.timestamp)
 }
 
-func (m *endMessage) getLatency() float64 {
+func (m *endMessage[K]) getLatency() float64 {
 	return m.latency
 }
 
-func (m *endMessage) setStatus(ms messageStatus) {
+func (m *endMessage[K]) setStatus(ms messageStatus) {
 	m.status.addStatus(ms)
 }
 
-func (m *endMessage) unsetStatus(ms messageStatus) {
+func (m *endMessage[K]) unsetStatus(ms messageStatus) {
 	m.status.removeStatus(ms)
 }
 
-func (m *endMessage) getStatus() messageStatus {
+func (m *endMessage[K]) getStatus() messageStatus {
 	return m.status
 }
 
 // same identifies one message 'm' as having the same identity with an'other' message 'o',
 // having identical requests and being from the same worker (identical worker signatures).
-func (m *endMessage) same(o message) bool {
+func (m *endMessage[K]) same(o message[K]) bool {
 	return m.signature() == o.signature() && m.request() == o.request()
 }
 
@@ -185,6 +214,13 @@ const (
 	msFailure                                   // 1 << 3 which is 00001000
 	msWaitlist                                  // 1 << 4 which is 00010000
 	msFinalizeFailure                           // 1 << 5 which is 00100000
+	msAdmission                                 // 1 << 6 which is 01000000
+	msPreempted                                 // 1 << 7 which is 10000000
+	msFinalizing                                // 1 << 8
+	msAbandoned                                 // 1 << 9
+	msFollowed                                  // 1 << 10
+	msMerged                                    // 1 << 11
+	msRevalidating                              // 1 << 12
 )
 
 // addStatus idempotently adds the passed status bits to the message status.
@@ -210,6 +246,12 @@ func (m messageStatus) results() string {
 	if m&msSuccess != 0 {
 		return "success"
 	}
+	if m&msMerged != 0 {
+		return "merge"
+	}
+	if m&msFollowed != 0 {
+		return "follow"
+	}
 	if m&msCease != 0 {
 		return "cease"
 	}
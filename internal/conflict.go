@@ -0,0 +1,208 @@
+package internal
+
+import "sort"
+
+// SetConflictScheduling enables conflict scheduling: requests for the same key are serialized only
+// where they conflict, as reported by interfaces.Conflicter, so non-conflicting requests (such as
+// updates to different fields of one record) proceed concurrently.  Requests are admitted when
+// they conflict with no processing entry, and otherwise wait in the conflict index, granted in
+// arrival order.  Requests not implementing interfaces.Conflicter conflict with every request for
+// their keys.  Supersession applies only between conflicting exclusive requests.
+func SetConflictScheduling(enabled bool) SupervisorOption {
+	return func(c *config) error {
+		c.conflictScheduling = enabled
+		return nil
+	}
+}
+
+// conflictIndex stores the pending messages (awaiting a grant of all their keys) indexed by each
+// of their keys, in arrival order, so conflicts are only checked between messages sharing a key.
+// Messages reserving a key range are stored in an interval index instead.
+type conflictIndex[K comparable] struct {
+	buckets map[K][]message[K]
+	ranges  intervals[K]
+	order   keyIndex[K]
+	count   int
+}
+
+func newConflictIndex[K comparable]() *conflictIndex[K] {
+	return &conflictIndex[K]{
+		buckets: make(map[K][]message[K]),
+	}
+}
+
+// add indexes message m at each of its keys, in arrival order, or at its key range.
+func (ci *conflictIndex[K]) add(m message[K]) {
+	ci.count++
+	if lo, hi, ok := m.keyset().span(); ok {
+		ci.ranges.insert(lo, hi, m)
+		return
+	}
+	for _, key := range m.keyset().keys {
+		bucket := ci.buckets[key]
+		if len(bucket) == 0 {
+			ci.order.add(key)
+		}
+		i := len(bucket)
+		for i > 0 && arrival(bucket[i-1]) > arrival(m) {
+			i--
+		}
+		bucket = append(bucket, nil)
+		copy(bucket[i+1:], bucket[i:])
+		bucket[i] = m
+		ci.buckets[key] = bucket
+	}
+}
+
+// remove removes the exact message from the index, returning false if not found.
+func (ci *conflictIndex[K]) remove(m message[K]) bool {
+	if isRange(m) {
+		found := ci.ranges.remove(m)
+		if found {
+			ci.count--
+		}
+		return found
+	}
+	found := false
+	for _, key := range m.keyset().keys {
+		bucket := ci.buckets[key]
+		for i, o := range bucket {
+			if o.same(m) {
+				bucket = append(bucket[:i:i], bucket[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if len(bucket) == 0 {
+			delete(ci.buckets, key)
+			ci.order.remove(key)
+			continue
+		}
+		ci.buckets[key] = bucket
+	}
+	if found {
+		ci.count--
+	}
+	return found
+}
+
+// find returns the indexed message which is the exact message m.
+func (ci *conflictIndex[K]) find(m message[K]) (message[K], bool) {
+	if isRange(m) {
+		return ci.ranges.find(m)
+	}
+	for _, o := range ci.buckets[m.keyset().key] {
+		if o.same(m) {
+			return o, true
+		}
+	}
+	return nil, false
+}
+
+// contains determines if the exact message is stored in the index.
+func (ci *conflictIndex[K]) contains(m message[K]) bool {
+	_, found := ci.find(m)
+	return found
+}
+
+// bucket returns the messages indexed at key, in arrival order.
+func (ci *conflictIndex[K]) bucket(key K) []message[K] {
+	return ci.buckets[key]
+}
+
+// keysIn returns the keys within [lo, hi) indexed by any message, in key order.
+func (ci *conflictIndex[K]) keysIn(lo, hi K) []K {
+	return ci.order.in(ci.buckets, lo, hi)
+}
+
+// candidates returns the distinct messages indexed at any of keys, in arrival order, excluding
+// messages reserving a key range.
+func (ci *conflictIndex[K]) candidates(keys []K) []message[K] {
+	if len(keys) == 1 {
+		return append([]message[K](nil), ci.buckets[keys[0]]...)
+	}
+	var msgs []message[K]
+	seen := make(map[message[K]]struct{})
+	for _, key := range keys {
+		msgs = appendUnseen(msgs, seen, ci.buckets[key])
+	}
+	sortByArrival(msgs)
+	return msgs
+}
+
+// drain removes and returns every message in the index, in arrival order.
+func (ci *conflictIndex[K]) drain() []message[K] {
+	var msgs []message[K]
+	seen := make(map[message[K]]struct{})
+	for _, bucket := range ci.buckets {
+		msgs = appendUnseen(msgs, seen, bucket)
+	}
+	msgs = append(msgs, ci.ranges.messages()...)
+	sortByArrival(msgs)
+	ci.buckets = make(map[K][]message[K])
+	ci.order.reset()
+	ci.ranges.entries = nil
+	ci.count = 0
+	return msgs
+}
+
+// length returns the number of messages in the index.
+func (ci *conflictIndex[K]) length() int {
+	return ci.count
+}
+
+// appendUnseen appends the messages not already seen to msgs.
+func appendUnseen[K comparable](msgs []message[K], seen map[message[K]]struct{}, add []message[K]) []message[K] {
+	for _, m := range add {
+		if _, ok := seen[m]; !ok {
+			seen[m] = struct{}{}
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// sortByArrival sorts the messages in arrival order.
+func sortByArrival[K comparable](msgs []message[K]) {
+	sort.Slice(msgs, func(i, j int) bool {
+		return arrival(msgs[i]) < arrival(msgs[j])
+	})
+}
+
+// displacePending applies supersession between exclusive begin message m and the exclusive
+// pending messages it conflicts with, under conflict scheduling.  If a pending message is not
+// superseded by m, m is ceased (or follows it) and false is returned.  Otherwise each pending
+// message superseded by m is displaced, following m where follow mode is enabled, and messages
+// waiting behind the displaced messages are then promoted where now grantable.
+func (s *Supervisor[K]) displacePending(m message[K], keys []K) bool {
+	if !s.conflictScheduling || shared(m) {
+		return true
+	}
+	var displaced []message[K]
+	var released []K
+	for _, p := range s.pending.candidates(keys) {
+		if !s.contendsAny(m, p, keys) {
+			continue
+		}
+		if err := s.callSupersedes(m.request(), p.request()); err != nil {
+			if !s.follow(m, p, err) {
+				s.cease(m, wrapOutcome(ErrSuperseded, err))
+			}
+			return false
+		}
+		displaced = append(displaced, p)
+	}
+	for _, p := range displaced {
+		s.pending.remove(p)
+		s.metrics.DecWaitingMapDepth()
+		err := s.callSupersedes(p.request(), m.request())
+		if !s.follow(p, m, err) {
+			s.cease(p, wrapOutcome(ErrDisplaced, err))
+		}
+		released = append(released, p.keyset().keys...)
+	}
+	if len(released) > 0 {
+		s.promotePending(released)
+	}
+	return true
+}
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// fieldReq is a testReq updating fields of the record, conflicting only with requests updating
+// any of the same fields.
+type fieldReq struct {
+	testReq
+	fields []string
+}
+
+func (p *fieldReq) ConflictsWith(o interfaces.Request[int64]) bool {
+	other, ok := o.(*fieldReq)
+	if !ok {
+		return true
+	}
+	for _, f := range p.fields {
+		for _, of := range other.fields {
+			if f == of {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func Test_conflictIndex(t *testing.T) {
+	newMsg := func(seq uint64, keys ...int64) message[int64] {
+		return &beginMessage[int64]{
+			req: &multiReq{testReq: testReq{key: keys[0]}, keys: keys[1:]},
+			seq: seq,
+		}
+	}
+	first, second, third := newMsg(1, 1, 2), newMsg(2, 2), newMsg(3, 3, 1)
+
+	ci := newConflictIndex[int64]()
+	ci.add(third)
+	ci.add(first)
+	ci.add(second)
+	if ci.length() != 3 {
+		t.Errorf("expected 3 messages indexed, got %d", ci.length())
+	}
+	if got := ci.bucket(1); len(got) != 2 || got[0] != first || got[1] != third {
+		t.Errorf("expected key 1 indexed in arrival order, got %v", got)
+	}
+	if got := ci.candidates([]int64{2, 3}); len(got) != 3 || got[0] != first || got[1] != second ||
+		got[2] != third {
+		t.Errorf("expected distinct candidates in arrival order, got %v", got)
+	}
+
+	if !ci.remove(first) || ci.contains(first) || ci.remove(first) {
+		t.Error("expected message removed once")
+	}
+	if got := ci.bucket(2); len(got) != 1 || got[0] != second {
+		t.Errorf("expected message removed at each key, got %v", got)
+	}
+	if got := ci.drain(); len(got) != 2 || got[0] != second || got[1] != third || ci.length() != 0 {
+		t.Errorf("expected remaining messages drained in arrival order, got %v", got)
+	}
+}
+
+func Test_SupervisorConflicts(t *testing.T) {
+	type req struct {
+		name   string
+		value  int64
+		fields []string // fields updated, or a request not implementing Conflicter if empty
+	}
+	tests := map[string]tableTest[req]{
+		"disabled serializes by key": {
+			hold:    []req{{name: "held", value: 9, fields: []string{"a"}}},
+			reqs:    []req{{name: "b", value: 10, fields: []string{"b"}}},
+			waiting: 1,
+			wantRun: []string{"b"},
+		},
+		"non-conflicting run concurrently": {
+			opts:    []SupervisorOption{SetConflictScheduling(true)},
+			hold:    []req{{name: "held", value: 9, fields: []string{"a"}}},
+			reqs:    []req{{name: "b", value: 10, fields: []string{"b"}}},
+			early:   []string{"b"},
+			wantRun: []string{"b"},
+		},
+		"non-conflicting never superseded": {
+			opts:    []SupervisorOption{SetConflictScheduling(true)},
+			hold:    []req{{name: "held", value: 20, fields: []string{"a"}}},
+			reqs:    []req{{name: "b", value: 10, fields: []string{"b"}}},
+			early:   []string{"b"},
+			wantRun: []string{"b"},
+		},
+		"conflicting waits": {
+			opts:    []SupervisorOption{SetConflictScheduling(true)},
+			hold:    []req{{name: "held", value: 9, fields: []string{"a"}}},
+			reqs:    []req{{name: "ab", value: 10, fields: []string{"a", "b"}}},
+			waiting: 1,
+			wantRun: []string{"ab"},
+		},
+		"conflicting superseded": {
+			opts:     []SupervisorOption{SetConflictScheduling(true)},
+			hold:     []req{{name: "held", value: 20, fields: []string{"a"}}},
+			reqs:     []req{{name: "a", value: 10, fields: []string{"a"}}},
+			wantErrs: map[string]error{"a": ErrSuperseded},
+		},
+		"conflicting waiting displaced": {
+			opts: []SupervisorOption{SetConflictScheduling(true)},
+			hold: []req{{name: "held", value: 9, fields: []string{"a"}}},
+			reqs: []req{
+				{name: "ab", value: 10, fields: []string{"a", "b"}},
+				{name: "a", value: 11, fields: []string{"a"}},
+			},
+			waiting:  1,
+			wantRun:  []string{"a"},
+			wantErrs: map[string]error{"ab": ErrDisplaced},
+		},
+		"non-conflicting waiting retained": {
+			opts: []SupervisorOption{SetConflictScheduling(true)},
+			hold: []req{{name: "held", value: 9, fields: []string{"a"}}},
+			reqs: []req{
+				{name: "a", value: 10, fields: []string{"a"}},
+				{name: "b", value: 11, fields: []string{"b"}},
+			},
+			waiting: 1,
+			early:   []string{"b"},
+			wantRun: []string{"b", "a"},
+		},
+		"non-implementing conflicts": {
+			opts:    []SupervisorOption{SetConflictScheduling(true)},
+			hold:    []req{{name: "held", value: 9}},
+			reqs:    []req{{name: "b", value: 10, fields: []string{"b"}}},
+			waiting: 1,
+			wantRun: []string{"b"},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		if len(r.fields) == 0 {
+			return r.name, &testReq{key: 1, value: r.value}
+		}
+		return r.name, &fieldReq{testReq: testReq{key: 1, value: r.value}, fields: r.fields}
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runTableTest(t, tc, newReq)
+		})
+	}
+}
+
+// Test_SupervisorConflictsInFlight confirms thousands of non-conflicting requests for one key are
+// held concurrently under conflict scheduling.
+func Test_SupervisorConflictsInFlight(t *testing.T) {
+	const inFlight = 2000
+	arbiter, db, ctx, _, li, err := testSetup(SetConflictScheduling(true))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	leases := make([]*Lease[int64], 0, inFlight)
+	for i := 0; i < inFlight; i++ {
+		r := &fieldReq{testReq: testReq{key: 1, value: int64(i + 1)}, fields: []string{fmt.Sprint(i)}}
+		setupTestItem(&r.testReq, db)
+		lease, err := arbiter.Acquire(ctx, r)
+		if err != nil {
+			t.Fatalf("expected Acquire %d to succeed, got: %v", i, err)
+		}
+		leases = append(leases, lease)
+	}
+	waitForGauge(t, li, at.ProcessingMapDepth, inFlight)
+	for _, lease := range leases {
+		lease.Abort()
+	}
+	waitForGauge(t, li, at.ProcessingMapDepth, 0)
+}
+
+// Benchmark_conflictDisplacement displaces many pending requests for one key with a request
+// superseding all of them, under conflict scheduling.
+func Benchmark_conflictDisplacement(b *testing.B) {
+	const pending = 200
+	b.ReportAllocs()
+	arbiter, db, ctx, _, li, err := testSetup(SetConflictScheduling(true))
+	if err != nil {
+		b.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	noop := func(context.Context) error { return nil }
+	fields := make([]string, pending)
+	for i := range fields {
+		fields[i] = fmt.Sprint(i)
+	}
+	var value int64
+	for n := 0; n < b.N; n++ {
+		b.StopTimer()
+		// The held request conflicts with every request, so each waits in the conflict index.
+		value++
+		held := &testReq{key: 1, value: value}
+		setupTestItem(held, db)
+		lease, err := arbiter.Acquire(ctx, held)
+		if err != nil {
+			b.Fatalf("expected Acquire to succeed, got: %v", err)
+		}
+		tickets := make([]*Ticket, 0, pending)
+		for _, f := range fields {
+			value++
+			r := &fieldReq{testReq: testReq{key: 1, value: value}, fields: []string{f}}
+			setupTestItem(&r.testReq, db)
+			tickets = append(tickets, arbiter.Submit(ctx, r, noop))
+		}
+		waitForGauge(b, li, at.WaitingMapDepth, pending)
+
+		b.StartTimer()
+		value++
+		r := &fieldReq{testReq: testReq{key: 1, value: value}, fields: fields}
+		setupTestItem(&r.testReq, db)
+		ticket := arbiter.Submit(ctx, r, noop)
+		for _, t := range tickets {
+			<-t.Done()
+		}
+		b.StopTimer()
+
+		if err := lease.Commit(); err != nil {
+			b.Fatalf("expected held request to succeed, got: %v", err)
+		}
+		<-ticket.Done()
+	}
+}
@@ -0,0 +1,326 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// dependentEntry is a begin message awaiting completion of the requests for the keys it depends
+// on (see interfaces.DependentRequest), before being arbitrated for its own keys.
+type dependentEntry[K comparable] struct {
+	m      message[K]
+	deps   []K
+	queued time.Time
+}
+
+// dependentIndex stores the messages awaiting dependencies, indexed by each key they depend on and
+// by each key they hold, so only the messages concerned by a key are examined.  Messages holding a
+// key range are stored apart.
+type dependentIndex[K comparable] struct {
+	awaiting map[K][]*dependentEntry[K]
+	holding  map[K][]*dependentEntry[K]
+	ranges   []*dependentEntry[K]
+	count    int
+}
+
+func newDependentIndex[K comparable]() *dependentIndex[K] {
+	return &dependentIndex[K]{
+		awaiting: make(map[K][]*dependentEntry[K]),
+		holding:  make(map[K][]*dependentEntry[K]),
+	}
+}
+
+// add indexes entry e at each key it depends on, and each key it holds.
+func (di *dependentIndex[K]) add(e *dependentEntry[K]) {
+	di.count++
+	for _, k := range e.deps {
+		di.awaiting[k] = append(di.awaiting[k], e)
+	}
+	if isRange(e.m) {
+		di.ranges = append(di.ranges, e)
+		return
+	}
+	for _, k := range e.m.keyset().keys {
+		di.holding[k] = append(di.holding[k], e)
+	}
+}
+
+// remove removes entry e from the index.
+func (di *dependentIndex[K]) remove(e *dependentEntry[K]) {
+	di.count--
+	for _, k := range e.deps {
+		removeEntry(di.awaiting, k, e)
+	}
+	if isRange(e.m) {
+		for i, o := range di.ranges {
+			if o == e {
+				di.ranges = append(di.ranges[:i:i], di.ranges[i+1:]...)
+				break
+			}
+		}
+		return
+	}
+	for _, k := range e.m.keyset().keys {
+		removeEntry(di.holding, k, e)
+	}
+}
+
+// removeEntry removes entry e from the entries indexed at key, removing key once it has none.
+func removeEntry[K comparable](index map[K][]*dependentEntry[K], key K, e *dependentEntry[K]) {
+	entries := index[key]
+	for i, o := range entries {
+		if o == e {
+			entries = append(entries[:i:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(index, key)
+		return
+	}
+	index[key] = entries
+}
+
+// find returns the entry for the exact message m.
+func (di *dependentIndex[K]) find(m message[K]) (*dependentEntry[K], bool) {
+	entries := di.ranges
+	if !isRange(m) {
+		entries = di.holding[m.keyset().key]
+	}
+	for _, e := range entries {
+		if e.m.same(m) {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// contains determines if entry e is stored in the index.
+func (di *dependentIndex[K]) contains(e *dependentEntry[K]) bool {
+	for _, o := range di.awaiting[e.deps[0]] {
+		if o == e {
+			return true
+		}
+	}
+	return false
+}
+
+// holders returns the entries for messages holding key, where holds reports whether a message
+// holding a key range holds key.
+func (di *dependentIndex[K]) holders(key K, holds func(m message[K], key K) bool) []*dependentEntry[K] {
+	entries := di.holding[key]
+	for _, e := range di.ranges {
+		if holds(e.m, key) {
+			entries = append(entries[:len(entries):len(entries)], e)
+		}
+	}
+	return entries
+}
+
+// dependingOn returns the distinct entries depending on any of the freed keys, in arrival order,
+// where inRange reports whether a key lies within a freed key range.
+func (di *dependentIndex[K]) dependingOn(f *freedKeys[K], inRange func(key, lo, hi K) bool) []*dependentEntry[K] {
+	var entries []*dependentEntry[K]
+	seen := make(map[*dependentEntry[K]]struct{})
+	add := func(es []*dependentEntry[K]) {
+		for _, e := range es {
+			if _, ok := seen[e]; !ok {
+				seen[e] = struct{}{}
+				entries = append(entries, e)
+			}
+		}
+	}
+	for k := range f.keys {
+		add(di.awaiting[k])
+	}
+	if len(f.ranges) > 0 {
+		for k, es := range di.awaiting {
+			for _, r := range f.ranges {
+				if inRange(k, r.lo, r.hi) {
+					add(es)
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return arrival(entries[i].m) < arrival(entries[j].m)
+	})
+	return entries
+}
+
+// drain removes and returns every message in the index, in arrival order.
+func (di *dependentIndex[K]) drain() []message[K] {
+	var msgs []message[K]
+	seen := make(map[*dependentEntry[K]]struct{})
+	for _, es := range di.awaiting {
+		for _, e := range es {
+			if _, ok := seen[e]; !ok {
+				seen[e] = struct{}{}
+				msgs = append(msgs, e.m)
+			}
+		}
+	}
+	sortByArrival(msgs)
+	*di = *newDependentIndex[K]()
+	return msgs
+}
+
+// length returns the number of messages in the index.
+func (di *dependentIndex[K]) length() int {
+	return di.count
+}
+
+// freedKeys collects the keys, and key ranges, released since messages awaiting dependencies were
+// last examined.
+type freedKeys[K comparable] struct {
+	keys   map[K]struct{}
+	ranges []interval[K]
+}
+
+// empty reports whether no key has been released.
+func (f *freedKeys[K]) empty() bool {
+	return len(f.keys) == 0 && len(f.ranges) == 0
+}
+
+// reset clears the released keys.
+func (f *freedKeys[K]) reset() {
+	for k := range f.keys {
+		delete(f.keys, k)
+	}
+	f.ranges = f.ranges[:0]
+}
+
+// freeKeys records the keys, or key range, of message m as released, so messages depending on them
+// are examined once the current message is processed.
+func (s *Supervisor[K]) freeKeys(m message[K]) {
+	if s.dependents.length() == 0 {
+		return
+	}
+	if lo, hi, ok := m.keyset().span(); ok {
+		s.freed.ranges = append(s.freed.ranges, interval[K]{lo: lo, hi: hi})
+		return
+	}
+	for _, k := range m.keyset().keys {
+		s.freed.keys[k] = struct{}{}
+	}
+}
+
+// freeKey records key as released, as freeKeys.
+func (s *Supervisor[K]) freeKey(key K) {
+	if s.dependents.length() > 0 {
+		s.freed.keys[key] = struct{}{}
+	}
+}
+
+// awaitDependencies holds begin message m while any key it depends on has a processing or waiting
+// entry, returning false if m has no such dependencies, so is arbitrated immediately.  Messages
+// whose dependencies form a cycle are ceased with ErrDependencyCycle.
+func (s *Supervisor[K]) awaitDependencies(m message[K]) bool {
+	deps := m.keyset().deps
+	if len(deps) == 0 {
+		return false
+	}
+	if s.dependencyCycle(m, deps) {
+		s.cease(m, wrapOutcome(ErrInvalid, fmt.Errorf("%w: key %v depends on %v", ErrDependencyCycle,
+			m.keyset().key, deps)))
+		return true
+	}
+	if !s.dependenciesBusy(m, deps) {
+		return false
+	}
+	s.dependents.add(&dependentEntry[K]{m: m, deps: deps, queued: time.Now()})
+	s.waitlist(m)
+	return true
+}
+
+// dependencyCycle reports whether the keys deps, depended on by message m, lead back to a key of
+// m, either directly or through the dependencies of messages awaiting their own dependencies.
+// Only messages of this supervisor are considered, which is complete for a ShardedSupervisor as it
+// confines dependencies to the shard of the request.
+func (s *Supervisor[K]) dependencyCycle(m message[K], deps []K) bool {
+	visited := make(map[K]struct{})
+	next := append([]K(nil), deps...)
+	for len(next) > 0 {
+		key := next[len(next)-1]
+		next = next[:len(next)-1]
+		if _, ok := visited[key]; ok {
+			continue
+		}
+		visited[key] = struct{}{}
+		if s.holdsKey(m, key) {
+			return true
+		}
+		for _, e := range s.dependents.holders(key, s.holdsKey) {
+			next = append(next, e.deps...)
+		}
+	}
+	return false
+}
+
+// dependenciesBusy reports whether any of deps, depended on by message m, is held or awaited by
+// another message: processing, awaiting admission or validation, waiting, or itself awaiting
+// dependencies.
+func (s *Supervisor[K]) dependenciesBusy(m message[K], deps []K) bool {
+	for _, k := range deps {
+		if len(s.processing.holders(k)) > 0 || len(s.waiting.queues[k]) > 0 ||
+			len(s.pending.bucket(k)) > 0 || len(s.validating[k]) > 0 {
+			return true
+		}
+		if s.less != nil && (len(s.processing.ranges.containing(k)) > 0 ||
+			len(s.pending.ranges.containing(k)) > 0) {
+			return true
+		}
+		for _, e := range s.dependents.holders(k, s.holdsKey) {
+			if !e.m.same(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// holdsKey reports whether message m holds key, as one of its keys or within its key range.
+func (s *Supervisor[K]) holdsKey(m message[K], key K) bool {
+	if lo, hi, ok := m.keyset().span(); ok {
+		return s.less != nil && s.inRange(key, lo, hi)
+	}
+	return containsKey(m.keyset().keys, key)
+}
+
+// releaseDependents arbitrates each message depending on keys released since last examined, once
+// its dependencies are complete, in arrival order.
+func (s *Supervisor[K]) releaseDependents() {
+	for !s.freed.empty() {
+		entries := s.dependents.dependingOn(&s.freed, s.inRange)
+		s.freed.reset()
+		for _, e := range entries {
+			if !s.dependents.contains(e) || s.dependenciesBusy(e.m, e.deps) {
+				continue
+			}
+			s.dependents.remove(e)
+			s.metrics.DecWaitingMapDepth()
+			s.expiry.remove(e.m)
+			s.metrics.DependencyWait(timeElapsedInSeconds(e.queued))
+			// The released message may complete at once, freeing keys other messages depend on.
+			s.arbitrate(e.m)
+		}
+	}
+}
+
+// removeDependent removes the exact message from the messages awaiting dependencies, returning the
+// message stored, or false if not found.
+func (s *Supervisor[K]) removeDependent(m message[K]) (message[K], bool) {
+	e, found := s.dependents.find(m)
+	if !found {
+		return nil, false
+	}
+	s.dependents.remove(e)
+	return e.m, true
+}
+
+// drainDependents removes and returns every message awaiting dependencies, in arrival order.
+func (s *Supervisor[K]) drainDependents() []message[K] {
+	return s.dependents.drain()
+}
@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// dependReq is a testReq applied only once requests for the keys it depends on have completed.
+type dependReq struct {
+	testReq
+	deps []int64
+}
+
+func (p *dependReq) DependsOn() []int64 {
+	return p.deps
+}
+
+func Test_dependentIndex(t *testing.T) {
+	newEntry := func(seq uint64, key int64, deps ...int64) *dependentEntry[int64] {
+		m := &beginMessage[int64]{req: &dependReq{testReq: testReq{key: key}, deps: deps}, seq: seq}
+		return &dependentEntry[int64]{m: m, deps: deps}
+	}
+	first, second, third := newEntry(1, 2, 1), newEntry(2, 3, 2), newEntry(3, 4, 1, 3)
+
+	di := newDependentIndex[int64]()
+	for _, e := range []*dependentEntry[int64]{third, first, second} {
+		di.add(e)
+	}
+	if di.length() != 3 {
+		t.Errorf("expected 3 entries indexed, got %d", di.length())
+	}
+	holds := func(m message[int64], key int64) bool { return false }
+	if got := di.holders(3, holds); len(got) != 1 || got[0] != second {
+		t.Errorf("expected entry holding key 3, got %v", got)
+	}
+	freed := freedKeys[int64]{keys: map[int64]struct{}{1: {}, 2: {}}}
+	if got := di.dependingOn(&freed, nil); len(got) != 3 || got[0] != first || got[1] != second ||
+		got[2] != third {
+		t.Errorf("expected distinct entries depending on freed keys in arrival order, got %v", got)
+	}
+
+	if e, found := di.find(second.m); !found || e != second {
+		t.Errorf("expected entry found by message, got %v", e)
+	}
+	di.remove(second)
+	if di.contains(second) || len(di.holders(3, holds)) != 0 {
+		t.Error("expected entry removed at each key")
+	}
+	if got := di.drain(); len(got) != 2 || got[0] != first.m || got[1] != third.m || di.length() != 0 {
+		t.Errorf("expected remaining messages drained in arrival order, got %v", got)
+	}
+}
+
+func Test_SupervisorDependencies(t *testing.T) {
+	type req struct {
+		name  string
+		key   int64
+		value int64
+		deps  []int64
+	}
+	tests := map[string]struct {
+		tableTest[req]
+		wantWait int64 // dependency wait observations
+	}{
+		"waits for in-flight dependency": {
+			tableTest: tableTest[req]{
+				hold:    []req{{name: "parent", key: 1, value: 9}},
+				reqs:    []req{{name: "child", key: 2, value: 10, deps: []int64{1}}},
+				waiting: 1,
+				wantRun: []string{"child"},
+				ordered: true,
+			},
+			wantWait: 1,
+		},
+		"waits for waiting dependency": {
+			tableTest: tableTest[req]{
+				hold: []req{{name: "held", key: 1, value: 9}},
+				reqs: []req{
+					{name: "parent", key: 1, value: 10},
+					{name: "child", key: 2, value: 10, deps: []int64{1}},
+				},
+				waiting: 2,
+				wantRun: []string{"parent", "child"},
+				ordered: true,
+			},
+			wantWait: 1,
+		},
+		"idle dependency proceeds": {
+			tableTest: tableTest[req]{
+				hold:    []req{{name: "held", key: 3, value: 9}},
+				reqs:    []req{{name: "child", key: 2, value: 10, deps: []int64{1}}},
+				early:   []string{"child"},
+				wantRun: []string{"child"},
+				ordered: true,
+			},
+		},
+		"waits for every dependency": {
+			tableTest: tableTest[req]{
+				hold: []req{
+					{name: "parent", key: 1, value: 9},
+					{name: "other", key: 3, value: 9},
+				},
+				reqs:    []req{{name: "child", key: 2, value: 10, deps: []int64{1, 3}}},
+				waiting: 1,
+				wantRun: []string{"child"},
+				ordered: true,
+			},
+			wantWait: 1,
+		},
+		"chained dependents in order": {
+			tableTest: tableTest[req]{
+				hold: []req{{name: "parent", key: 1, value: 9}},
+				reqs: []req{
+					{name: "child", key: 2, value: 10, deps: []int64{1}},
+					{name: "grandchild", key: 3, value: 10, deps: []int64{2}},
+				},
+				waiting: 2,
+				wantRun: []string{"child", "grandchild"},
+				ordered: true,
+			},
+			wantWait: 2,
+		},
+		"self dependency rejected": {
+			tableTest: tableTest[req]{
+				reqs:     []req{{name: "child", key: 2, value: 10, deps: []int64{2}}},
+				wantErrs: map[string]error{"child": ErrDependencyCycle},
+				ordered:  true,
+			},
+		},
+		"dependency cycle rejected": {
+			tableTest: tableTest[req]{
+				hold: []req{{name: "parent", key: 1, value: 9}},
+				reqs: []req{
+					{name: "child", key: 2, value: 10, deps: []int64{1}},
+					{name: "cycle", key: 3, value: 10, deps: []int64{2, 1}},
+					{name: "back", key: 1, value: 10, deps: []int64{3}},
+				},
+				waiting:  2,
+				wantRun:  []string{"child", "cycle"},
+				wantErrs: map[string]error{"back": ErrDependencyCycle},
+				ordered:  true,
+			},
+			wantWait: 2,
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		if len(r.deps) == 0 {
+			return r.name, &testReq{key: r.key, value: r.value}
+		}
+		return r.name, &dependReq{testReq: testReq{key: r.key, value: r.value}, deps: r.deps}
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			li := runTableTest(t, tc.tableTest, newReq)
+			if got := li.SnapMetrics().HistogramSummaries()[at.DependencyWait]; got != tc.wantWait {
+				t.Errorf("expected %d dependency wait observations, got %d", tc.wantWait, got)
+			}
+		})
+	}
+}
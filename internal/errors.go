@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Outcome errors classify why a request did not complete successfully.  Where the outcome was
+// caused by a consumer (or context) error, it is wrapped in an OutcomeError, so errors.Is
+// matches both the outcome error and the underlying error.
+var (
+	// ErrInvalid indicates the request was ceased because Valid returned an error.
+	ErrInvalid = errors.New("request invalid")
+	// ErrSuperseded indicates the request was ceased because it did not supersede the in-flight
+	// (or newest waiting) request for its key.
+	ErrSuperseded = errors.New("request superseded")
+	// ErrDisplaced indicates the request was ceased after being displaced from the waiting list
+	// (or admission queue) by a superseding request.
+	ErrDisplaced = errors.New("request displaced")
+	// ErrCanceled indicates the request context ended while awaiting a supervisor response.
+	ErrCanceled = errors.New("request canceled")
+	// ErrWorkFailed indicates the work function returned an error, so the request was not finalized.
+	ErrWorkFailed = errors.New("work failed")
+	// ErrFinalizeFailed indicates Finalize returned an error.
+	ErrFinalizeFailed = errors.New("finalize failed")
+	// ErrLeaderFailed indicates the request followed by a follower (see SetFollowMode) did not
+	// succeed.
+	ErrLeaderFailed = errors.New("followed request failed")
+	// ErrKeyRange indicates the key range of a request could not be arbitrated, as it is empty or
+	// the key type has no order (see SetKeyOrder).  It is wrapped by ErrInvalid.
+	ErrKeyRange = errors.New("invalid key range")
+	// ErrDependencyCycle indicates the keys a request depends on (see
+	// interfaces.DependentRequest) lead back to its own keys through requests awaiting their
+	// dependencies, so it could never proceed.  It is wrapped by ErrInvalid.
+	ErrDependencyCycle = errors.New("dependency cycle")
+	// ErrCrossShard indicates the keys of a request are assigned to more than one shard of a
+	// ShardedSupervisor, so cannot be arbitrated together.  It is wrapped by ErrInvalid.
+	ErrCrossShard = errors.New("keys assigned to more than one shard")
+)
+
+// OutcomeError wraps the underlying error causing a request outcome with the outcome error.
+type OutcomeError struct {
+	Kind error // outcome error, such as ErrInvalid or ErrSuperseded.
+	Err  error // underlying consumer or context error.
+}
+
+func (e *OutcomeError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Kind, e.Err)
+}
+
+// Is reports whether target is the outcome error.
+func (e *OutcomeError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap returns the underlying consumer or context error.
+func (e *OutcomeError) Unwrap() error {
+	return e.Err
+}
+
+// wrapOutcome wraps err with the outcome error kind.  Errors generated by the supervisor itself,
+// and recovered panics, are returned unwrapped.
+func wrapOutcome(kind, err error) error {
+	var pe *PanicError
+	if err == nil || errors.As(err, &pe) || errors.Is(err, ErrWaitingQueueFull) || errors.Is(err, ErrShutdown) ||
+		errors.Is(err, ErrPreempted) || errors.Is(err, ErrWorkStuck) {
+		return err
+	}
+	return &OutcomeError{Kind: kind, Err: err}
+}
+
+// ErrWaitingQueueFull indicates a request was ceased because the waiting queue for its key
+// was already at the configured depth, and the waiting policy does not displace entries.
+var ErrWaitingQueueFull = errors.New("waiting queue full")
+
+// ErrPreempted indicates an in-flight request was superseded while processing with preemption
+// enabled.  Its work context was canceled, and the request was not finalized.
+var ErrPreempted = errors.New("request preempted")
+
+// ErrWorkStuck indicates the work of an in-flight request ran past the watchdog threshold with
+// force release enabled (see SetWatchdogRelease).  Its key was released and its work context
+// canceled, and the request was not finalized.
+var ErrWorkStuck = errors.New("work stuck past watchdog threshold")
+
+// ErrPending indicates the outcome of a submitted request is not yet available.
+var ErrPending = errors.New("request pending")
+
+// ErrLeaseReleased indicates a Lease was already released by Commit or Abort.
+var ErrLeaseReleased = errors.New("lease already released")
+
+// ErrQueueFull indicates TryWithWorker could not send the begin message, because the supervisor
+// queue channel was full.
+var ErrQueueFull = errors.New("supervisor queue full")
+
+// ErrLoadShed indicates a request was shed by the configured load shedding policy.
+var ErrLoadShed = errors.New("request shed under load")
+
+// ErrRateLimited indicates a request was ceased because rate limit tokens were not available,
+// with the RateLimitReject policy.
+var ErrRateLimited = errors.New("request rate limited")
+
+// ErrExpired indicates a request was ceased because it was still waiting once its expiry (see
+// interfaces.Expiring) or the waiting TTL (see SetWaitingTTL) passed.
+var ErrExpired = errors.New("request expired while waiting")
+
+// ErrShutdown indicates a request was ceased because the supervisor is shutting down (or has stopped).
+var ErrShutdown = errors.New("supervisor shutdown")
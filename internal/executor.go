@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/btsomogyi/arbiter/interfaces"
+
+	"github.com/btsomogyi/arbiter/logging"
+)
+
+// SetExecutorPool sets the number of executors running Valid and Finalize off the supervisor
+// goroutine, so slow request calls for one key do not stall arbitration of other keys.  Keys
+// remain reserved while their calls execute, and results are applied in order on the supervisor
+// goroutine.  Zero (the default) runs Valid and Finalize inline.
+func SetExecutorPool(n uint) SupervisorOption {
+	return func(c *config) error {
+		c.executors = n
+		return nil
+	}
+}
+
+// operation identifies the request call performed by an executor.
+type operation int
+
+const (
+	validOp    operation = iota // Request.Valid() on a begin message
+	finalizeOp                  // Request.Finalize() on an end message
+	promoteOp                   // Request.Valid() on a begin message promoted from waiting
+	admitOp                     // Request.Valid() on a begin message admitted to a processing slot
+)
+
+func (o operation) String() string {
+	return [...]string{"valid", "finalize", "promote", "admit"}[o]
+}
+
+var _ message[int64] = (*completionMessage[int64])(nil)
+
+// completionMessage carries the result of a request call made by an executor back to the
+// supervisor goroutine, on the same queue as begin and end messages.  All other message
+// behavior is delegated to the target message the call was made for.
+type completionMessage[K comparable] struct {
+	target message[K]
+	op     operation
+	err    error
+}
+
+func (m *completionMessage[K]) request() interfaces.Request[K] {
+	return m.target.request()
+}
+
+func (m *completionMessage[K]) keyset() *keyset[K] {
+	return m.target.keyset()
+}
+
+func (m *completionMessage[K]) respond(state state, signal signal, err error) {
+	m.target.respond(state, signal, err)
+}
+
+func (m *completionMessage[K]) signature() *worker[K] {
+	return m.target.signature()
+}
+
+func (m *completionMessage[K]) same(o message[K]) bool {
+	return m.target.same(o)
+}
+
+func (m *completionMessage[K]) setLatency() {
+}
+
+func (m *completionMessage[K]) getLatency() float64 {
+	return m.target.getLatency()
+}
+
+func (m *completionMessage[K]) setStatus(ms messageStatus) {
+	m.target.setStatus(ms)
+}
+
+func (m *completionMessage[K]) unsetStatus(ms messageStatus) {
+	m.target.unsetStatus(ms)
+}
+
+func (m *completionMessage[K]) getStatus() messageStatus {
+	return m.target.getStatus()
+}
+
+// dispatch runs the request call for target on an executor, or adds it to the backlog if all
+// executors are busy.  dispatch never blocks the supervisor goroutine.
+func (s *Supervisor[K]) dispatch(target message[K], op operation) {
+	c := &completionMessage[K]{target: target, op: op}
+	if s.executing >= s.executors {
+		s.backlog = append(s.backlog, c)
+		return
+	}
+	s.executing++
+	go s.execute(c)
+}
+
+// execute makes the request call and returns the completion to the supervisor.
+func (s *Supervisor[K]) execute(c *completionMessage[K]) {
+	switch c.op {
+	case validOp, promoteOp, admitOp:
+		c.err = s.callValid(c.request())
+	case finalizeOp:
+		c.err = s.callFinalize(c.request())
+	}
+	select {
+	case s.queue <- c:
+		s.metrics.IncQueueChanDepth()
+	case <-s.stopped:
+		// Supervisor has stopped, so the result can no longer be applied.
+	}
+}
+
+// processCompletion applies the result of an executor request call, and dispatches the next
+// backlogged call to the freed executor.
+func (s *Supervisor[K]) processCompletion(c *completionMessage[K]) {
+	s.executing--
+	if len(s.backlog) > 0 {
+		next := s.backlog[0]
+		s.backlog[0] = nil
+		s.backlog = s.backlog[1:]
+		s.executing++
+		go s.execute(next)
+	}
+
+	switch c.op {
+	case validOp:
+		s.validated(c.target, c.err)
+	case finalizeOp:
+		s.finalized(c.target, c.err)
+	case promoteOp, admitOp:
+		s.revalidated(c.target, c.op, c.err)
+	default:
+		// This is by design an unreachable condition, but left in to detect future modifications that
+		// may violate that design.
+		s.logger.DPanic("Unexpected operation in completion message", []logging.LogTuple{
+			{Field: "request key", Value: c.keyset().key},
+			{Field: "operation", Value: fmt.Sprint(c.op)},
+		})
+	}
+}
+
+// validate queues begin message m for validation by an executor.  Begin messages are validated
+// one at a time per key, so they are enqueued in the order they arrived.
+func (s *Supervisor[K]) validate(m message[K]) {
+	key := m.keyset().key
+	s.validating[key] = append(s.validating[key], m)
+	if len(s.validating[key]) == 1 {
+		s.dispatch(m, validOp)
+	}
+}
+
+// validated applies the result of Valid for the begin message at the head of the validating
+// list for its key, and dispatches validation of the next begin message for the key.
+func (s *Supervisor[K]) validated(m message[K], err error) {
+	key := m.keyset().key
+	pending := s.validating[key][1:]
+	if len(pending) == 0 {
+		delete(s.validating, key)
+		s.freeKey(key)
+	} else {
+		s.validating[key] = pending
+		s.dispatch(pending[0], validOp)
+	}
+
+	switch {
+	case m.getStatus()&msAbandoned != 0:
+		// Worker ended before validation completed, so there is no one to respond to.
+	case s.draining:
+		s.cease(m, ErrShutdown)
+	case err != nil:
+		s.cease(m, wrapOutcome(ErrInvalid, err))
+	default:
+		s.enqueMessage(m)
+	}
+}
+
+// revalidateHeld validates begin message m again once it is granted its keys, after waiting or
+// awaiting admission (op promoteOp or admitOp respectively), returning false if m is no longer
+// valid, in which case it is ceased and the caller releases its keys.  With an executor pool, m
+// reserves its keys in the processing map while Valid executes (along with its processing slot
+// if admitted), and true is returned with activation continuing in revalidated.
+func (s *Supervisor[K]) revalidateHeld(m message[K], op operation) bool {
+	if s.executors == 0 {
+		if err := s.callValid(m.request()); err != nil {
+			s.cease(m, wrapOutcome(ErrInvalid, err))
+			return false
+		}
+		s.resume(m, op)
+		return true
+	}
+	s.processing.add(m)
+	m.setStatus(msRevalidating)
+	if op == admitOp {
+		s.running++
+	}
+	s.dispatch(m, op)
+	return true
+}
+
+// resume activates begin message m once valid.  Admitted messages have a processing slot, so
+// proceed at once.  Promoted messages whose activation is ceased by rate limits release their keys.
+func (s *Supervisor[K]) resume(m message[K], op operation) {
+	if op == admitOp {
+		s.proceedMessage(m)
+		return
+	}
+	if !s.activateMessage(m) {
+		s.releaseMessage(m)
+	}
+}
+
+// revalidated applies the result of Valid for begin message m, granted its keys after waiting or
+// awaiting admission, releasing its keys and any processing slot unless it proceeds.
+func (s *Supervisor[K]) revalidated(m message[K], op operation, err error) {
+	s.processing.remove(m)
+	m.unsetStatus(msRevalidating)
+	if op == admitOp {
+		s.running--
+	}
+
+	switch {
+	case m.getStatus()&msAbandoned != 0:
+		// Worker ended while revalidating, so there is no one to respond to.
+		s.releaseMessage(m)
+	case s.draining:
+		s.cease(m, ErrShutdown)
+		s.releaseMessage(m)
+	case err != nil:
+		s.logger.Debug("Request invalidated once granted its keys", []logging.LogTuple{
+			{Field: "key", Value: m.keyset().key},
+			{Field: "error", Value: err},
+		})
+		s.cease(m, wrapOutcome(ErrInvalid, err))
+		s.releaseMessage(m)
+	default:
+		s.resume(m, op)
+	}
+	if op == admitOp {
+		s.admitNext()
+	}
+}
+
+// abandonValidating removes the begin message matching end message m from the validating list
+// for its key, returning false if not found.  A begin message already dispatched for validation
+// is marked abandoned, and discarded once validated.
+func (s *Supervisor[K]) abandonValidating(m message[K]) bool {
+	// A begin message revalidating once granted its keys is discarded once revalidated.
+	if inProcessMsg, found := s.processing.find(m); found && inProcessMsg.getStatus()&msRevalidating != 0 {
+		inProcessMsg.setStatus(msAbandoned)
+		return true
+	}
+	key := m.keyset().key
+	pending := s.validating[key]
+	for i, v := range pending {
+		if !v.same(m) {
+			continue
+		}
+		if i == 0 {
+			v.setStatus(msAbandoned)
+		} else {
+			s.validating[key] = append(pending[:i], pending[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// finalize dispatches Finalize of end message m to an executor.  The processing entry for the
+// key is marked finalizing, and remains reserved until the result is applied.
+func (s *Supervisor[K]) finalize(m message[K]) {
+	if inProcessMsg, found := s.processing.find(m); found {
+		inProcessMsg.setStatus(msFinalizing)
+	}
+	s.dispatch(m, finalizeOp)
+}
+
+// finalized responds to end message m with the result of Finalize, and purges the processing
+// entry for the key.
+func (s *Supervisor[K]) finalized(m message[K], err error) {
+	if err != nil {
+		m.setStatus(msFinalizeFailure)
+		s.pushMessageMetrics(m)
+		m.respond(endState, failureSignal, wrapOutcome(ErrFinalizeFailed, err))
+	} else {
+		s.pushMessageMetrics(m)
+		m.respond(endState, successSignal, nil)
+	}
+	s.purgeMessage(m)
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_ExecutorPool(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup(SetExecutorPool(2))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	slow := requestDefs["record1version9"]
+	superseding := requestDefs["record1version10"]
+	other := requestDefs["record2version10"]
+	for _, r := range []*testReq{&slow, &superseding, &other} {
+		setupTestItem(r, db)
+	}
+	finalizing := make(chan struct{})
+	release := make(chan struct{})
+	finalize := slow.finalize
+	slow.finalize = func() error {
+		close(finalizing)
+		<-release
+		return finalize()
+	}
+
+	slowTicket := arbiter.Submit(ctx, &slow, func(context.Context) error { return nil })
+	<-finalizing
+
+	// Arbitration of other keys continues while Finalize is blocked.
+	if err := arbiter.WithWorker(ctx, &other, func(context.Context) error { return nil }); err != nil {
+		t.Errorf("expected request for other key to succeed, got: %v", err)
+	}
+
+	// The key remains reserved until Finalize completes.
+	supersedingTicket := arbiter.Submit(ctx, &superseding, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+	if err := supersedingTicket.Result(); !errors.Is(err, ErrPending) {
+		t.Errorf("expected superseding request pending, got: %v", err)
+	}
+
+	close(release)
+	if err := slowTicket.Wait(ctx); err != nil {
+		t.Errorf("expected slow request to succeed, got: %v", err)
+	}
+	if err := supersedingTicket.Wait(ctx); err != nil {
+		t.Errorf("expected superseding request to succeed, got: %v", err)
+	}
+	checkDb(t, db, &superseding)
+	checkDb(t, db, &other)
+}
+
+func Test_ExecutorPoolValidating(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup(SetExecutorPool(1))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	canceled := requestDefs["record1version9"]
+	stale := requestDefs["record1version8"]
+	next := requestDefs["record1version10"]
+	for _, r := range []*testReq{&canceled, &stale, &next} {
+		setupTestItem(r, db)
+	}
+	validating := make(chan struct{})
+	release := make(chan struct{})
+	valid := canceled.valid
+	canceled.valid = func() error {
+		close(validating)
+		<-release
+		return valid()
+	}
+
+	// A request canceled while validating is discarded once validated.
+	canceledTicket := arbiter.Submit(ctx, &canceled, func(context.Context) error { return nil })
+	<-validating
+	canceledTicket.Cancel()
+	if err := canceledTicket.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled request to return %v, got: %v", context.Canceled, err)
+	}
+
+	// Requests for the same key are validated in arrival order, behind the canceled request.
+	staleTicket := arbiter.Submit(ctx, &stale, func(context.Context) error { return nil })
+	nextTicket := arbiter.Submit(ctx, &next, func(context.Context) error { return nil })
+	time.Sleep(10 * time.Millisecond)
+	for _, ticket := range []*Ticket{staleTicket, nextTicket} {
+		if err := ticket.Result(); !errors.Is(err, ErrPending) {
+			t.Errorf("expected request pending behind validating request, got: %v", err)
+		}
+	}
+
+	close(release)
+	if err := staleTicket.Wait(ctx); err != nil {
+		t.Errorf("expected stale request to succeed, got: %v", err)
+	}
+	if err := nextTicket.Wait(ctx); err != nil {
+		t.Errorf("expected next request to succeed, got: %v", err)
+	}
+	waitForGauge(t, li, at.ProcessingMapDepth, 0)
+	checkDb(t, db, &next)
+}
+
+func Test_ExecutorPoolRevalidating(t *testing.T) {
+	tests := map[string]struct {
+		opts    []SupervisorOption
+		granted string // request granted its key once the held request commits
+	}{
+		"promoted from waiting": {
+			opts:    []SupervisorOption{SetRevalidate(true)},
+			granted: "record1version10",
+		},
+		"admitted to a processing slot": {
+			opts:    []SupervisorOption{SetMaxProcessing(1)},
+			granted: "record2version10",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup(append(tc.opts, SetExecutorPool(2))...)
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			held := requestDefs["record1version9"]
+			granted := requestDefs[tc.granted]
+			later := requestDefs["record1version11"]
+			stale := requestDefs["record3version30"]
+			for _, r := range []*testReq{&held, &granted, &later, &stale} {
+				setupTestItem(r, db)
+			}
+			db.set(stale.key, stale.value)
+			validating := make(chan struct{})
+			release := make(chan struct{})
+			var calls int
+			valid := granted.valid
+			granted.valid = func() error {
+				// Block the second call, made once the request is granted its key.
+				if calls++; calls == 2 {
+					close(validating)
+					<-release
+				}
+				return valid()
+			}
+
+			lease, err := arbiter.Acquire(ctx, &held)
+			if err != nil {
+				t.Fatalf("expected Acquire to succeed, got: %v", err)
+			}
+			grantedTicket := arbiter.Submit(ctx, &granted, func(context.Context) error { return nil })
+			if tc.granted == "record1version10" {
+				waitForGauge(t, li, at.WaitingMapDepth, 1)
+			} else {
+				waitForGauge(t, li, at.AdmissionQueueDepth, 1)
+			}
+			if err := lease.Commit(); err != nil {
+				t.Fatalf("expected held request to succeed, got: %v", err)
+			}
+			<-validating
+
+			// Arbitration continues while the granted request is revalidated.
+			if err := arbiter.WithWorker(ctx, &stale, func(context.Context) error { return nil }); !errors.Is(err, ErrInvalid) {
+				t.Errorf("expected stale request to return %v, got: %v", ErrInvalid, err)
+			}
+
+			// The key of the granted request remains reserved until it is revalidated.
+			laterTicket := arbiter.Submit(ctx, &later, func(context.Context) error { return nil })
+			time.Sleep(10 * time.Millisecond)
+			for _, ticket := range []*Ticket{grantedTicket, laterTicket} {
+				if err := ticket.Result(); !errors.Is(err, ErrPending) {
+					t.Errorf("expected request pending behind revalidating request, got: %v", err)
+				}
+			}
+
+			close(release)
+			if err := grantedTicket.Wait(ctx); err != nil {
+				t.Errorf("expected granted request to succeed, got: %v", err)
+			}
+			if err := laterTicket.Wait(ctx); err != nil {
+				t.Errorf("expected later request to succeed, got: %v", err)
+			}
+			waitForGauge(t, li, at.ProcessingMapDepth, 0)
+			checkDb(t, db, &later)
+		})
+	}
+}
+
+// Compare inline and executor pool Finalize, where Finalize is slow relative to the work.
+func Benchmark_slowFinalize(b *testing.B) {
+	modes := map[string][]SupervisorOption{
+		"inline":        nil,
+		"executor pool": {SetExecutorPool(16)},
+	}
+	for name, opts := range modes {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				b.StopTimer()
+				arbiter, db, ctx, wg, _, err := testSetup(opts...)
+				if err != nil {
+					b.Fatalf("failed to setup test: %e", err)
+				}
+				go arbiter.Process()
+
+				var requests []*testReq
+				for i := 1; i <= benchmarkIterations; i++ {
+					request := testReq{
+						key:   int64(i),
+						value: 1,
+					}
+					setupTestItem(&request, db)
+					finalize := request.finalize
+					request.finalize = func() error {
+						time.Sleep(time.Millisecond)
+						return finalize()
+					}
+					requests = append(requests, &request)
+				}
+
+				b.StartTimer()
+				for _, req := range requests {
+					wg.Add(1)
+					go func(r *testReq) {
+						defer wg.Done()
+						_ = arbiter.WithWorker(ctx, r, func(context.Context) error { return nil })
+					}(req)
+				}
+				wg.Wait()
+				b.StopTimer()
+				arbiter.Terminate()
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btsomogyi/arbiter/logging"
+)
+
+const (
+	// expiryTick is the resolution of waiting message expiry.
+	expiryTick = 10 * time.Millisecond
+	// expirySlots is the number of ticks in one revolution of the expiry timer wheel.
+	expirySlots = 512
+)
+
+// SetWaitingTTL sets the maximum time a request may wait for its keys (in the waiting map, as a
+// pending message, or awaiting dependencies) before it is ceased with ErrExpired, so requests
+// waiting behind long running work are not applied once stale.  Requests implementing
+// interfaces.Expiring expire at the earlier of their expiry and the TTL.  Zero (the default)
+// places no limit on waiting time.
+func SetWaitingTTL(d time.Duration) SupervisorOption {
+	return func(c *config) error {
+		if d < 0 {
+			return fmt.Errorf("waiting TTL must not be negative")
+		}
+		c.waitingTTL = d
+		return nil
+	}
+}
+
+// expiryEntry is a waiting message scheduled to expire at deadline, at index within slot of the
+// timer wheel.  The begin message holds its entry, so it is unscheduled once it stops waiting.
+type expiryEntry[K comparable] struct {
+	m           message[K]
+	deadline    time.Time
+	slot, index int
+}
+
+// timerWheel schedules the expiry of waiting messages in slots of one tick each, so scheduling is
+// constant time regardless of the number of waiting messages.  Deadlines beyond one revolution of
+// the wheel remain in their slot until the revolution in which they pass.  Messages leaving the
+// waiting messages before their deadline are removed from their slot in constant time.
+type timerWheel[K comparable] struct {
+	slots   [][]*expiryEntry[K]
+	tick    time.Duration
+	cursor  int       // slot of the most recent tick advanced past.
+	current time.Time // time of the most recent tick advanced past.
+	count   int
+	timer   *time.Timer
+	wake    <-chan time.Time
+	wakeAt  time.Time
+}
+
+func newTimerWheel[K comparable](tick time.Duration, slots int) *timerWheel[K] {
+	return &timerWheel[K]{
+		slots: make([][]*expiryEntry[K], slots),
+		tick:  tick,
+	}
+}
+
+// add schedules message m to expire at deadline.
+func (w *timerWheel[K]) add(now time.Time, m message[K], deadline time.Time) {
+	if w.count == 0 {
+		w.current = now
+	}
+	// The slot is the number of ticks until the deadline, within one revolution of the wheel.
+	d := deadline.Sub(w.current)
+	ticks := d / w.tick
+	if d%w.tick != 0 {
+		ticks++
+	}
+	slots := 1
+	if ticks > 1 {
+		slots = int((ticks-1)%time.Duration(len(w.slots))) + 1
+	}
+	slot := (w.cursor + slots) % len(w.slots)
+	e := &expiryEntry[K]{m: m, deadline: deadline, slot: slot, index: len(w.slots[slot])}
+	w.slots[slot] = append(w.slots[slot], e)
+	setExpiry(m, e)
+	w.count++
+	if at := w.current.Add(time.Duration(slots) * w.tick); w.wake == nil || at.Before(w.wakeAt) {
+		w.schedule(now, at)
+	}
+}
+
+// advance moves the wheel forward to now, returning the messages whose deadline has passed.  At
+// most one revolution of slots is examined, however long since the wheel last advanced.
+func (w *timerWheel[K]) advance(now time.Time) []message[K] {
+	w.wake = nil
+	ticks := int(now.Sub(w.current) / w.tick)
+	visit := ticks
+	if visit > len(w.slots) {
+		visit = len(w.slots)
+	}
+	var expired []message[K]
+	for i := 1; i <= visit && w.count > 0; i++ {
+		slot := (w.cursor + i) % len(w.slots)
+		kept := w.slots[slot][:0]
+		for _, e := range w.slots[slot] {
+			if e.deadline.After(now) {
+				e.index = len(kept)
+				kept = append(kept, e)
+				continue
+			}
+			setExpiry(e.m, nil)
+			expired = append(expired, e.m)
+			w.count--
+		}
+		tail := w.slots[slot][len(kept):]
+		for i := range tail {
+			tail[i] = nil
+		}
+		w.slots[slot] = kept
+	}
+	if ticks > 0 {
+		w.cursor = (w.cursor + ticks) % len(w.slots)
+		w.current = w.current.Add(time.Duration(ticks) * w.tick)
+	}
+	w.arm(now)
+	return expired
+}
+
+// remove unschedules begin message m, once it is no longer waiting.  The wake remains armed, so
+// may fire with no message expired.
+func (w *timerWheel[K]) remove(m message[K]) {
+	bm, ok := m.(*beginMessage[K])
+	if !ok || bm.expiry == nil {
+		return
+	}
+	e := bm.expiry
+	entries := w.slots[e.slot]
+	last := entries[len(entries)-1]
+	entries[e.index], last.index = last, e.index
+	entries[len(entries)-1] = nil
+	w.slots[e.slot] = entries[:len(entries)-1]
+	bm.expiry = nil
+	w.count--
+	if w.count == 0 && w.timer != nil {
+		w.timer.Stop()
+		w.wake = nil
+	}
+}
+
+// setExpiry records the expiry entry of begin message m, or nil once unscheduled.
+func setExpiry[K comparable](m message[K], e *expiryEntry[K]) {
+	if bm, ok := m.(*beginMessage[K]); ok {
+		bm.expiry = e
+	}
+}
+
+// arm schedules the wake for the next tick with messages scheduled, if any.
+func (w *timerWheel[K]) arm(now time.Time) {
+	if w.count == 0 {
+		if w.timer != nil {
+			w.timer.Stop()
+		}
+		w.wake = nil
+		return
+	}
+	for i := 1; i <= len(w.slots); i++ {
+		if len(w.slots[(w.cursor+i)%len(w.slots)]) > 0 {
+			w.schedule(now, w.current.Add(time.Duration(i)*w.tick))
+			return
+		}
+	}
+}
+
+// schedule arms the wake timer to fire at time at.
+func (w *timerWheel[K]) schedule(now, at time.Time) {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.NewTimer(at.Sub(now))
+	w.wake = w.timer.C
+	w.wakeAt = at
+}
+
+// wakeup returns the channel signaling waiting messages may have expired, or nil if none are
+// scheduled.
+func (w *timerWheel[K]) wakeup() <-chan time.Time {
+	return w.wake
+}
+
+// length returns the number of messages scheduled.
+func (w *timerWheel[K]) length() int {
+	return w.count
+}
+
+// waitlist records begin message m as waiting, and schedules its expiry.  The deadline is assigned
+// when first waitlisted, and kept if m waits again, such as once its dependencies complete.
+func (s *Supervisor[K]) waitlist(m message[K]) {
+	m.setStatus(msWaitlist)
+	s.metrics.IncWaitingMapDepth()
+
+	bm, ok := m.(*beginMessage[K])
+	if !ok || bm.expiry != nil {
+		return
+	}
+	now := time.Now()
+	if bm.expires.IsZero() {
+		if s.waitingTTL > 0 {
+			bm.expires = now.Add(s.waitingTTL)
+		}
+		if e := s.callExpires(m.request()); !e.IsZero() && (bm.expires.IsZero() || e.Before(bm.expires)) {
+			bm.expires = e
+		}
+	}
+	if !bm.expires.IsZero() {
+		s.expiry.add(now, m, bm.expires)
+	}
+}
+
+// expireWaiting ceases the waiting messages whose expiry has passed with ErrExpired, promoting
+// messages waiting behind them.
+func (s *Supervisor[K]) expireWaiting(now time.Time) {
+	for _, m := range s.expiry.advance(now) {
+		if !s.removeWaiting(m) {
+			// No longer waiting, so does not expire.
+			continue
+		}
+		s.logger.Debug("Supervisor expiring waiting request", []logging.LogTuple{
+			{Field: "key", Value: m.keyset().key},
+		})
+		s.metrics.Expired()
+		s.cease(m, ErrExpired)
+	}
+}
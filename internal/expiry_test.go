@@ -0,0 +1,200 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// expiringReq is a multiReq whose data goes stale at expires.
+type expiringReq struct {
+	multiReq
+	expires time.Time
+}
+
+func (p *expiringReq) Expires() time.Time {
+	return p.expires
+}
+
+func Test_timerWheel(t *testing.T) {
+	newMsg := func(key int64) message[int64] {
+		return &beginMessage[int64]{req: &testReq{key: key}}
+	}
+	first, second, later := newMsg(1), newMsg(2), newMsg(3)
+	start := time.Now()
+	w := newTimerWheel[int64](time.Millisecond, 4)
+	w.add(start, first, start.Add(time.Millisecond))
+	w.add(start, second, start.Add(3*time.Millisecond))
+	w.add(start, later, start.Add(10*time.Millisecond)) // beyond one revolution of the wheel
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		want    []message[int64]
+	}{
+		{name: "first expired", advance: 2 * time.Millisecond, want: []message[int64]{first}},
+		{name: "second expired", advance: 5 * time.Millisecond, want: []message[int64]{second}},
+		{name: "later retained past revolution", advance: 9 * time.Millisecond},
+		{name: "later expired", advance: 10 * time.Millisecond, want: []message[int64]{later}},
+	}
+	for _, tc := range tests {
+		got := w.advance(start.Add(tc.advance))
+		if len(got) != len(tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+			}
+		}
+	}
+	if w.length() != 0 || w.wakeup() != nil {
+		t.Errorf("expected empty wheel without wake, got %d scheduled", w.length())
+	}
+
+	// Messages no longer waiting are removed from their slot, leaving the others scheduled.
+	removed, kept := newMsg(4), newMsg(5)
+	w.add(start, newMsg(6), start.Add(20*time.Millisecond))
+	w.add(start, removed, start.Add(20*time.Millisecond))
+	w.add(start, kept, start.Add(20*time.Millisecond))
+	w.remove(removed)
+	w.remove(removed)
+	if w.length() != 2 || removed.(*beginMessage[int64]).expiry != nil {
+		t.Errorf("expected message removed once, got %d scheduled", w.length())
+	}
+	if got := w.advance(start.Add(20 * time.Millisecond)); len(got) != 2 || got[1] != kept {
+		t.Errorf("expected remaining messages expired, got %v", got)
+	}
+}
+
+// Test_SupervisorExpiryUnscheduled confirms requests leaving the waiting messages before their
+// deadline no longer remain scheduled to expire.
+func Test_SupervisorExpiryUnscheduled(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup(SetWaitingTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	held := &testReq{key: 1, value: 9}
+	setupTestItem(held, db)
+	lease, err := arbiter.Acquire(ctx, held)
+	if err != nil {
+		t.Fatalf("expected Acquire to succeed, got: %v", err)
+	}
+	promoted, canceled := &testReq{key: 1, value: 10}, &testReq{key: 2, value: 10}
+	setupTestItem(promoted, db)
+	setupTestItem(canceled, db)
+	pt := arbiter.Submit(ctx, promoted, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+	blocker, err := arbiter.Acquire(ctx, &testReq{key: 2, value: 9, valid: func() error { return nil }})
+	if err != nil {
+		t.Fatalf("expected Acquire to succeed, got: %v", err)
+	}
+	ct := arbiter.Submit(ctx, canceled, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 2)
+
+	ct.Cancel()
+	<-ct.Done()
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+	if err := lease.Commit(); err != nil {
+		t.Errorf("expected held request to succeed, got: %v", err)
+	}
+	<-pt.Done()
+	if err := pt.Result(); err != nil {
+		t.Errorf("expected promoted request to succeed, got: %v", err)
+	}
+	blocker.Abort()
+	waitForGauge(t, li, at.ProcessingMapDepth, 0)
+	if n := arbiter.expiry.length(); n != 0 {
+		t.Errorf("expected no expiry scheduled once nothing waits, got %d", n)
+	}
+}
+
+func Test_SupervisorExpiry(t *testing.T) {
+	const soon, never = 20 * time.Millisecond, time.Hour
+	type req struct {
+		name    string
+		key     int64
+		keys    []int64
+		value   int64
+		expires time.Duration // if set, the request expires this long after submission
+	}
+	tests := map[string]tableTest[req]{
+		"waiting TTL expires": {
+			opts:     []SupervisorOption{SetWaitingTTL(soon)},
+			hold:     []req{{name: "held", key: 1, value: 9}},
+			reqs:     []req{{name: "waiting", key: 1, value: 10}},
+			early:    []string{"waiting"},
+			wantErrs: map[string]error{"waiting": ErrExpired},
+		},
+		"request expiry": {
+			hold:     []req{{name: "held", key: 1, value: 9}},
+			reqs:     []req{{name: "waiting", key: 1, value: 10, expires: soon}},
+			early:    []string{"waiting"},
+			wantErrs: map[string]error{"waiting": ErrExpired},
+		},
+		"earlier request expiry than TTL": {
+			opts:     []SupervisorOption{SetWaitingTTL(never)},
+			hold:     []req{{name: "held", key: 1, value: 9}},
+			reqs:     []req{{name: "waiting", key: 1, value: 10, expires: soon}},
+			early:    []string{"waiting"},
+			wantErrs: map[string]error{"waiting": ErrExpired},
+		},
+		"promoted before expiry": {
+			opts:    []SupervisorOption{SetWaitingTTL(never)},
+			hold:    []req{{name: "held", key: 1, value: 9}},
+			reqs:    []req{{name: "waiting", key: 1, value: 10}},
+			waiting: 1,
+			wantRun: []string{"waiting"},
+		},
+		"proceeding request never expires": {
+			reqs:    []req{{name: "free", key: 1, value: 10, expires: -soon}},
+			early:   []string{"free"},
+			wantRun: []string{"free"},
+		},
+		"expired pending releases reserved keys": {
+			opts: []SupervisorOption{SetWaitingTTL(never)},
+			hold: []req{{name: "held", key: 1, value: 9}},
+			reqs: []req{
+				{name: "multi", key: 1, keys: []int64{2}, value: 10, expires: soon},
+				{name: "point", key: 2, value: 10},
+			},
+			early:    []string{"multi", "point"},
+			wantRun:  []string{"point"},
+			wantErrs: map[string]error{"multi": ErrExpired},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		if r.expires == 0 && len(r.keys) == 0 {
+			return r.name, &testReq{key: r.key, value: r.value}
+		}
+		er := &expiringReq{
+			multiReq: multiReq{testReq: testReq{key: r.key, value: r.value}, keys: r.keys},
+		}
+		if r.expires != 0 {
+			er.expires = time.Now().Add(r.expires)
+		}
+		return r.name, er
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			li := runTableTest(t, tc, newReq)
+			var expired int64
+			for _, err := range tc.wantErrs {
+				if errors.Is(err, ErrExpired) {
+					expired++
+				}
+			}
+			if got := li.SnapMetrics().Counters[at.Expired]; got != expired {
+				t.Errorf("expected %d expired, got %d", expired, got)
+			}
+		})
+	}
+}
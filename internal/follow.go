@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+)
+
+// SetFollowMode enables following: a request ceased as redundant, because an equal or newer
+// request for its key is in-flight or waiting, attaches to the request superseding it (similar to
+// singleflight).  The follower receives the outcome of the followed request once it completes,
+// rather than an immediate ErrSuperseded or ErrDisplaced.  Followers of a request which is itself
+// superseded (or preempted) follow the superseding request in turn.
+func SetFollowMode(enabled bool) SupervisorOption {
+	return func(c *config) error {
+		c.followMode = enabled
+		return nil
+	}
+}
+
+// follow attaches begin message m, ceased as redundant with err, to the leader message which
+// superseded it, along with any followers of m.  Returns false if m cannot follow (follow mode is
+// disabled, m was not ceased by supersession, or m is from Acquire), in which case m should be
+// ceased.
+func (s *Supervisor[K]) follow(m, leader message[K], err error) bool {
+	var pe *PanicError
+	if !s.followMode || leader == nil || errors.As(err, &pe) {
+		return false
+	}
+	if m.signature().lease {
+		s.transferFollowers(m, leader)
+		return false
+	}
+	s.attach(m, leader)
+	return true
+}
+
+// attach adds begin message m, and any followers of m, to the followers of the leader message.
+func (s *Supervisor[K]) attach(m, leader message[K]) {
+	s.transferFollowers(m, leader)
+	lw := leader.signature()
+	lw.followers = append(lw.followers, m)
+	m.setStatus(msFollowed)
+	s.pushMessageMetrics(m)
+}
+
+// transferFollowers moves the followers of message from to follow message to.
+func (s *Supervisor[K]) transferFollowers(from, to message[K]) {
+	fw, tw := from.signature(), to.signature()
+	if len(fw.followers) == 0 {
+		return
+	}
+	tw.followers = append(tw.followers, fw.followers...)
+	fw.followers = nil
+}
+
+// releaseFollowers responds to the followers of the worker with the outcome of its request, once
+// the final response (sig and err) is sent to the worker.  Followers of a request which did not
+// succeed receive ErrLeaderFailed, wrapping the error of the request.
+func (w *worker[K]) releaseFollowers(sig signal, err error) {
+	if sig != successSignal {
+		if err == nil {
+			err = ErrLeaderFailed
+		} else {
+			err = &OutcomeError{Kind: ErrLeaderFailed, Err: err}
+		}
+	}
+	for _, f := range w.followers {
+		f.signature().send(response{
+			state:  beginState,
+			sig:    sig,
+			err:    err,
+			status: f.getStatus(),
+			leader: w.current(),
+		})
+	}
+	w.followers = nil
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func Test_SupervisorFollow(t *testing.T) {
+	type want struct {
+		errs   []error // errors the outcome error must match (via errors.Is)
+		leader string  // request followed, if any
+	}
+	tests := map[string]struct {
+		opts    []SupervisorOption
+		hold    string   // request holding the key via a Lease while requests are submitted
+		reqs    []string // requests submitted in order
+		abort   bool     // abort the held request rather than commit
+		wantRun []string // requests whose work function runs
+		want    map[string]want
+	}{
+		"follow disabled": {
+			hold: "record1version10",
+			reqs: []string{"record1version9"},
+			want: map[string]want{
+				"record1version9": {errs: []error{ErrSuperseded}},
+			},
+		},
+		"follows in-flight": {
+			opts: []SupervisorOption{SetFollowMode(true)},
+			hold: "record1version10",
+			reqs: []string{"record1version9"},
+			want: map[string]want{
+				"record1version9": {leader: "record1version10"},
+			},
+		},
+		"in-flight failure": {
+			opts:  []SupervisorOption{SetFollowMode(true)},
+			hold:  "record1version10",
+			reqs:  []string{"record1version9"},
+			abort: true,
+			want: map[string]want{
+				"record1version9": {errs: []error{ErrLeaderFailed}, leader: "record1version10"},
+			},
+		},
+		"displaced follows superseding": {
+			opts:    []SupervisorOption{SetFollowMode(true)},
+			hold:    "record1version9",
+			reqs:    []string{"record1version10", "record1version11"},
+			wantRun: []string{"record1version11"},
+			want: map[string]want{
+				"record1version10": {leader: "record1version11"},
+				"record1version11": {},
+			},
+		},
+		"followers of displaced transfer": {
+			opts:    []SupervisorOption{SetFollowMode(true)},
+			hold:    "record1version9",
+			reqs:    []string{"record1version11", "record1version10", "record1version20"},
+			wantRun: []string{"record1version20"},
+			want: map[string]want{
+				"record1version10": {leader: "record1version20"},
+				"record1version11": {leader: "record1version20"},
+				"record1version20": {},
+			},
+		},
+		"followers of preempted transfer": {
+			opts:    []SupervisorOption{SetFollowMode(true), SetPreemption(true)},
+			hold:    "record1version9",
+			reqs:    []string{"record1version8", "record1version10"},
+			wantRun: []string{"record1version10"},
+			want: map[string]want{
+				"record1version8":  {leader: "record1version10"},
+				"record1version10": {},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, _, err := testSetup(tc.opts...)
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			requests := make(map[string]*testReq)
+			held := requestDefs[tc.hold]
+			setupTestItem(&held, db)
+			requests[tc.hold] = &held
+			lease, err := arbiter.Acquire(ctx, &held)
+			if err != nil {
+				t.Fatalf("expected Acquire to succeed, got: %v", err)
+			}
+
+			var mtx sync.Mutex
+			var ran []string
+			tickets := make(map[string]*Ticket)
+			for _, name := range tc.reqs {
+				name := name
+				r := requestDefs[name]
+				setupTestItem(&r, db)
+				requests[name] = &r
+				tickets[name] = arbiter.Submit(ctx, &r, func(context.Context) error {
+					mtx.Lock()
+					ran = append(ran, name)
+					mtx.Unlock()
+					return nil
+				})
+			}
+			// Begin messages are sent before Submit returns, so are processed ahead of the end message.
+			if tc.abort {
+				lease.Abort()
+			} else if err := lease.Commit(); err != nil && !errors.Is(err, ErrPreempted) {
+				t.Errorf("expected held request to succeed, got: %v", err)
+			}
+
+			for name, want := range tc.want {
+				<-tickets[name].Done()
+				got := tickets[name].outcome
+				for _, wantErr := range want.errs {
+					if !errors.Is(got.Err, wantErr) {
+						t.Errorf("%s: expected outcome error to match %v, got: %v", name, wantErr, got.Err)
+					}
+				}
+				if len(want.errs) == 0 && got.Err != nil {
+					t.Errorf("%s: expected no outcome error, got: %v", name, got.Err)
+				}
+				if got.Followed != (want.leader != "") {
+					t.Errorf("%s: expected followed %v, got: %+v", name, want.leader != "", got)
+				}
+				if want.leader != "" && got.Leader != requests[want.leader] {
+					t.Errorf("%s: expected to follow %s, got: %v", name, want.leader, got.Leader)
+				}
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if len(ran) != len(tc.wantRun) {
+				t.Fatalf("expected work run for %v, got %v", tc.wantRun, ran)
+			}
+			for i := range tc.wantRun {
+				if ran[i] != tc.wantRun[i] {
+					t.Errorf("expected work run for %v, got %v", tc.wantRun, ran)
+					break
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+// lockMode is the mode in which a message holds one of its keys.  A message holds its own keys
+// (see ownKeys) in its mode, and the ancestor keys on its path (see interfaces.HierarchicalRequest)
+// with the matching intention, as in intention locking.
+type lockMode int
+
+const (
+	lockIntentShared    lockMode = iota // lockIntentShared is held on ancestors of a shared message.
+	lockIntentExclusive                 // lockIntentExclusive is held on ancestors of an exclusive message.
+	lockShared                          // lockShared is held on the own keys of a shared message.
+	lockExclusive                       // lockExclusive is held on the own keys of an exclusive message.
+)
+
+// lockCompatible reports whether two messages may hold a key concurrently in the given modes.
+// Intentions are compatible with one another, so operations on different descendants proceed
+// concurrently, while an exclusive ancestor excludes every descendant and vice versa.
+var lockCompatible = [...][4]bool{
+	lockIntentShared:    {true, true, true, false},
+	lockIntentExclusive: {true, true, false, false},
+	lockShared:          {true, false, true, false},
+	lockExclusive:       {false, false, false, false},
+}
+
+// lockModeAt returns the mode in which message m holds key.
+func lockModeAt[K comparable](m message[K], key K) lockMode {
+	own := containsKey(m.keyset().own, key)
+	switch {
+	case own && shared(m):
+		return lockShared
+	case own:
+		return lockExclusive
+	case shared(m):
+		return lockIntentShared
+	default:
+		return lockIntentExclusive
+	}
+}
+
+// compatible reports whether messages a and b may hold key concurrently: their modes at key are
+// compatible, or with conflict scheduling both operate on key and neither conflicts with the
+// other.
+func (s *Supervisor[K]) compatible(a, b message[K], key K) bool {
+	am, bm := lockModeAt(a, key), lockModeAt(b, key)
+	if lockCompatible[am][bm] {
+		return true
+	}
+	return s.conflictScheduling && am >= lockShared && bm >= lockShared &&
+		!s.callConflicts(a.request(), b.request())
+}
+
+// contends reports whether messages m and p contend for key as exclusive messages operating on
+// it, so supersession applies between them.
+func (s *Supervisor[K]) contends(m, p message[K], key K) bool {
+	return lockModeAt(m, key) == lockExclusive && lockModeAt(p, key) == lockExclusive &&
+		!s.compatible(m, p, key)
+}
+
+// contendsAny reports whether messages m and p contend for any of keys.
+func (s *Supervisor[K]) contendsAny(m, p message[K], keys []K) bool {
+	for _, k := range keys {
+		if s.contends(m, p, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// pathReq is a testReq for a resource in a tree, at the end of its key path.
+type pathReq struct {
+	testReq
+	path []int64
+	mode interfaces.Mode
+}
+
+func (p *pathReq) GetPath() []int64 {
+	return p.path
+}
+
+func (p *pathReq) Mode() interfaces.Mode {
+	return p.mode
+}
+
+func Test_lockCompatible(t *testing.T) {
+	tests := map[string]struct {
+		a, b lockMode
+		want bool
+	}{
+		"intentions":                   {a: lockIntentExclusive, b: lockIntentShared, want: true},
+		"shared with intent shared":    {a: lockShared, b: lockIntentShared, want: true},
+		"shared with intent exclusive": {a: lockShared, b: lockIntentExclusive, want: false},
+		"exclusive with intent shared": {a: lockExclusive, b: lockIntentShared, want: false},
+		"shared":                       {a: lockShared, b: lockShared, want: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := lockCompatible[tc.a][tc.b]; got != tc.want {
+				t.Errorf("expected compatible %v, got %v", tc.want, got)
+			}
+			if got := lockCompatible[tc.b][tc.a]; got != tc.want {
+				t.Errorf("expected compatibility symmetric, got %v", got)
+			}
+		})
+	}
+}
+
+func Test_SupervisorHierarchy(t *testing.T) {
+	// Keys of a tenant, a cluster of the tenant, and two nodes of the cluster.
+	const tenant, cluster, node1, node2 = 1, 2, 3, 4
+	type req struct {
+		name   string
+		value  int64
+		path   []int64 // key path, ending with the key of the request
+		shared bool
+	}
+	tests := map[string]tableTest[req]{
+		"different nodes concurrent": {
+			hold:    []req{{name: "node1", value: 9, path: []int64{tenant, cluster, node1}}},
+			reqs:    []req{{name: "node2", value: 10, path: []int64{tenant, cluster, node2}}},
+			early:   []string{"node2"},
+			wantRun: []string{"node2"},
+		},
+		"ancestor waits for descendant": {
+			hold:    []req{{name: "node1", value: 20, path: []int64{tenant, cluster, node1}}},
+			reqs:    []req{{name: "tenant", value: 10, path: []int64{tenant}}},
+			waiting: 1,
+			wantRun: []string{"tenant"},
+		},
+		"descendant waits for ancestor": {
+			hold:    []req{{name: "cluster", value: 20, path: []int64{tenant, cluster}}},
+			reqs:    []req{{name: "node1", value: 10, path: []int64{tenant, cluster, node1}}},
+			waiting: 1,
+			wantRun: []string{"node1"},
+		},
+		"ancestor not starved by descendants": {
+			hold: []req{{name: "node1", value: 9, path: []int64{tenant, cluster, node1}}},
+			reqs: []req{
+				{name: "tenant", value: 10, path: []int64{tenant}},
+				{name: "node2", value: 10, path: []int64{tenant, cluster, node2}},
+			},
+			waiting: 2,
+			wantRun: []string{"tenant", "node2"},
+		},
+		"shared ancestor with shared descendant": {
+			hold:    []req{{name: "node1", value: 9, path: []int64{tenant, cluster, node1}, shared: true}},
+			reqs:    []req{{name: "tenant", value: 10, path: []int64{tenant}, shared: true}},
+			early:   []string{"tenant"},
+			wantRun: []string{"tenant"},
+		},
+		"shared ancestor waits for exclusive descendant": {
+			hold:    []req{{name: "node1", value: 9, path: []int64{tenant, cluster, node1}}},
+			reqs:    []req{{name: "tenant", value: 10, path: []int64{tenant}, shared: true}},
+			waiting: 1,
+			wantRun: []string{"tenant"},
+		},
+		"same node superseded": {
+			hold:     []req{{name: "held", value: 20, path: []int64{tenant, cluster, node1}}},
+			reqs:     []req{{name: "node1", value: 10, path: []int64{tenant, cluster, node1}}},
+			wantErrs: map[string]error{"node1": ErrSuperseded},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		pr := &pathReq{testReq: testReq{key: r.path[len(r.path)-1], value: r.value}, path: r.path}
+		if r.shared {
+			pr.mode = interfaces.Shared
+		}
+		return r.name, pr
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runTableTest(t, tc, newReq)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// keyset is the keys of a request, resolved once with any panic recovered (see resolveKeys), so
+// the supervisor never calls GetKey, GetKeys, GetPath, GetRange or DependsOn on the request itself.
+type keyset[K comparable] struct {
+	req    interfaces.Request[K] // request the keys were resolved from.
+	key    K                     // GetKey, identifying the request in logs and metrics.
+	own    []K                   // keys the request operates on (see ownKeys).
+	keys   []K                   // keys held by the request (see requestKeys).
+	lo, hi K                     // key range reserved by the request, if ranged.
+	ranged bool
+	deps   []K // keys the request depends on (see callDependsOn).
+}
+
+func newKeyset[K comparable](r interfaces.Request[K]) *keyset[K] {
+	ks := &keyset[K]{
+		req: r,
+		own: ownKeys(r),
+	}
+	ks.key = ks.own[0]
+	ks.keys = pathKeys(r, ks.own)
+	ks.lo, ks.hi, ks.ranged = rangeOf(r)
+	return ks
+}
+
+// span returns the key range [lo, hi) reserved by the request, or false if it reserves no range.
+func (ks *keyset[K]) span() (lo, hi K, ok bool) {
+	return ks.lo, ks.hi, ks.ranged
+}
+
+// resolveKeys resolves the keys of request r, recovering any panic.  A panic in any of the key
+// methods of r is reported as a panic in GetKey, while a panic in DependsOn leaves the request
+// without dependencies.
+func (s *Supervisor[K]) resolveKeys(r interfaces.Request[K]) (ks *keyset[K], err error) {
+	err = s.guard("GetKey", r, func() error {
+		ks = newKeyset(r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ks.deps = s.callDependsOn(r)
+	return ks, nil
+}
+
+// keyset returns the keys of the current request of the worker, resolved when the worker was
+// generated, or when its request was merged.
+func (w *worker[K]) keyset() *keyset[K] {
+	if w.keys == nil || w.keys.req != w.current() {
+		// Only reached by workers constructed without a supervisor.
+		w.keys = newKeyset(w.current())
+	}
+	return w.keys
+}
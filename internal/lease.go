@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"context"
+	"github.com/btsomogyi/arbiter/interfaces"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/btsomogyi/arbiter/logging"
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// Lease holds the processing entry of an acquired request until it is released by Commit or
+// Abort, for work that cannot be wrapped in a single closure.  A Lease is not safe for
+// concurrent use.  Leases whose context ends before they are released are aborted, and leases
+// garbage collected without being released are reported and aborted.
+type Lease[K comparable] struct {
+	s *Supervisor[K]
+	w *worker[K]
+	// released is shared with the goroutine aborting the Lease once its context ends.
+	released *atomic.Bool
+	// done is closed once the Lease is released.
+	done chan struct{}
+}
+
+// Acquire arbitrates request r, returning a Lease once the request is permitted to proceed.
+// If the request is ceased, the error is returned instead.  The caller must Commit or Abort
+// the Lease to release the key, otherwise the Lease is aborted once ctx ends.  Note that an
+// unreleased Lease acquired with a context which never ends holds the key for as long as the
+// Lease is referenced: it is only reported and aborted once garbage collected.
+func (s *Supervisor[K]) Acquire(ctx context.Context, r interfaces.Request[K]) (*Lease[K], error) {
+	ks, keyErr := s.resolveKeys(r)
+	return s.acquire(ctx, r, ks, keyErr)
+}
+
+// acquire arbitrates request r, with keys ks or key resolution error keyErr (see generateWorker),
+// as Acquire.
+func (s *Supervisor[K]) acquire(ctx context.Context, r interfaces.Request[K], ks *keyset[K], keyErr error) (*Lease[K], error) {
+	w, df := s.generateWorker(ctx, r, ks, keyErr)
+	w.lease = true
+
+	if err := w.sendBegin(false); err != nil {
+		df()
+		s.metrics.Transactions(w.duration(), telemetry.Labels{
+			"signal": failureSignal.String(),
+		})
+		return nil, err
+	}
+	beginResponse := w.recvResponse(beginState, ceaseSignal)
+	if beginResponse.sig != proceedSignal {
+		df()
+		s.metrics.Transactions(w.duration(), telemetry.Labels{
+			"signal": failureSignal.String(),
+		})
+		return nil, beginResponse.err
+	}
+
+	w.boundWork(s.workTimeout(r))
+	w.workStart = time.Now()
+	l := &Lease[K]{s: s, w: w, released: new(atomic.Bool), done: make(chan struct{})}
+	runtime.SetFinalizer(l, (*Lease[K]).leaked)
+	if ctx.Done() != nil {
+		// The copy shares the release state of l, but not its finalizer, so l is still garbage
+		// collected if leaked while ctx remains active.
+		go (&Lease[K]{s: s, w: w, released: l.released, done: l.done}).abortOnDone(ctx)
+	}
+	return l, nil
+}
+
+// Context returns the context of the acquired request, which ends with the context passed to
+// Acquire, is canceled if the request is preempted (see SetPreemption) or released by the
+// watchdog (see SetWatchdogRelease), and ends once the maximum work duration passes (see
+// SetMaxWorkDuration).
+func (l *Lease[K]) Context() context.Context {
+	return l.w.workContext()
+}
+
+// Commit releases the Lease with successSignal, returning the result of Finalize.  Commit of an
+// already released Lease returns ErrLeaseReleased.
+func (l *Lease[K]) Commit() error {
+	if !l.release() {
+		return ErrLeaseReleased
+	}
+	l.w.status = successSignal
+	l.s.metrics.Worktime(l.w.workDuration(), telemetry.Labels{
+		"signal": successSignal.String(),
+	})
+
+	l.w.sendEnd()
+	endResponse := l.w.recvResponse(endState, failureSignal)
+	l.w.deferredFunc()
+
+	if endResponse.sig != successSignal {
+		l.s.metrics.Transactions(l.w.duration(), telemetry.Labels{
+			"signal": failureSignal.String(),
+		})
+		return endResponse.err
+	}
+	l.s.metrics.Transactions(l.w.duration(), telemetry.Labels{
+		"signal": successSignal.String(),
+	})
+	return nil
+}
+
+// Abort releases the Lease with failureSignal, so the request is not finalized.  Abort of an
+// already released Lease has no effect.
+func (l *Lease[K]) Abort() {
+	if !l.release() {
+		return
+	}
+	l.abort()
+}
+
+// release marks the Lease released, returning false if it was already released.
+func (l *Lease[K]) release() bool {
+	if !l.released.CompareAndSwap(false, true) {
+		return false
+	}
+	runtime.SetFinalizer(l, nil)
+	close(l.done)
+	return true
+}
+
+// abortOnDone aborts the Lease once ctx ends, unless it is released first.
+func (l *Lease[K]) abortOnDone(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		if !l.release() {
+			return
+		}
+		l.s.logger.Debug("Lease aborted as its context ended", []logging.LogTuple{
+			{Field: "key", Value: l.w.key},
+			{Field: "error", Value: ctx.Err()},
+		})
+		l.abort()
+	case <-l.done:
+	}
+}
+
+// abort sends the end message with failureSignal.
+func (l *Lease[K]) abort() {
+	l.s.metrics.Worktime(l.w.workDuration(), telemetry.Labels{
+		"signal": failureSignal.String(),
+	})
+	l.s.metrics.Transactions(l.w.duration(), telemetry.Labels{
+		"signal": failureSignal.String(),
+	})
+	l.w.deferredFunc()
+}
+
+// leaked is the finalizer of an unreleased Lease, which reports and aborts it so the key is not
+// held indefinitely.
+func (l *Lease[K]) leaked() {
+	if !l.release() {
+		return
+	}
+	l.s.logger.Error("Lease garbage collected without Commit or Abort", []logging.LogTuple{
+		{Field: "key", Value: l.w.key},
+		{Field: "held", Value: time.Since(l.w.workStart).String()},
+	})
+	// Sending the end message may block, which must not happen on the finalizer goroutine.
+	go l.abort()
+}
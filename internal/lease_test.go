@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btsomogyi/arbiter/logging"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_Lease(t *testing.T) {
+	tests := map[string]struct {
+		current    string // request already stored before Acquire (optional)
+		release    func(*testing.T, *Lease[int64])
+		wantErr    error
+		wantDB     string
+		wantUpdate bool // expect store updated by the acquired request
+	}{
+		"commit finalizes": {
+			release: func(t *testing.T, l *Lease[int64]) {
+				if err := l.Commit(); err != nil {
+					t.Errorf("expected Commit to succeed, got: %v", err)
+				}
+				if err := l.Commit(); !errors.Is(err, ErrLeaseReleased) {
+					t.Errorf("expected second Commit to return %v, got: %v", ErrLeaseReleased, err)
+				}
+				l.Abort()
+			},
+			wantUpdate: true,
+		},
+		"abort does not finalize": {
+			release: func(t *testing.T, l *Lease[int64]) {
+				l.Abort()
+				l.Abort()
+				if err := l.Commit(); !errors.Is(err, ErrLeaseReleased) {
+					t.Errorf("expected Commit after Abort to return %v, got: %v", ErrLeaseReleased, err)
+				}
+			},
+		},
+		"invalid request not acquired": {
+			current: "record1version10",
+			wantErr: ErrInvalidRequest,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup()
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			acquired := requestDefs["record1version9"]
+			setupTestItem(&acquired, db)
+			if tc.current != "" {
+				current := requestDefs[tc.current]
+				db.set(current.key, current.value)
+			}
+			before := db.get(acquired.key)
+
+			lease, err := arbiter.Acquire(ctx, &acquired)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected Acquire error %v, got: %v", tc.wantErr, err)
+			}
+			if err != nil {
+				if lease != nil {
+					t.Errorf("expected no Lease with Acquire error")
+				}
+				waitForGauge(t, li, at.ProcessingMapDepth, 0)
+				return
+			}
+
+			// The key is held until the Lease is released.
+			waitForGauge(t, li, at.ProcessingMapDepth, 1)
+			if lease.Context().Err() != nil {
+				t.Errorf("expected Lease context active, got: %v", lease.Context().Err())
+			}
+			tc.release(t, lease)
+			waitForGauge(t, li, at.ProcessingMapDepth, 0)
+
+			if got := db.get(acquired.key); tc.wantUpdate && got != acquired.value || !tc.wantUpdate && got != before {
+				t.Errorf("unexpected store value %d after release", got)
+			}
+		})
+	}
+}
+
+func Test_LeaseAbortPromotesWaiting(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	acquired := requestDefs["record1version9"]
+	waiting := requestDefs["record1version10"]
+	setupTestItem(&acquired, db)
+	setupTestItem(&waiting, db)
+
+	lease, err := arbiter.Acquire(ctx, &acquired)
+	if err != nil {
+		t.Fatalf("expected Acquire to succeed, got: %v", err)
+	}
+	ticket := arbiter.Submit(ctx, &waiting, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+
+	lease.Abort()
+	if err := ticket.Wait(ctx); err != nil {
+		t.Errorf("expected waiting request to succeed, got: %v", err)
+	}
+	checkDb(t, db, &waiting)
+}
+
+func Test_LeaseContextEnded(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	acquired := requestDefs["record1version9"]
+	waiting := requestDefs["record1version10"]
+	setupTestItem(&acquired, db)
+	setupTestItem(&waiting, db)
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	lease, err := arbiter.Acquire(leaseCtx, &acquired)
+	if err != nil {
+		t.Fatalf("expected Acquire to succeed, got: %v", err)
+	}
+	ticket := arbiter.Submit(ctx, &waiting, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+
+	// Ending the context of the unreleased Lease aborts it, promoting the waiting request.
+	cancel()
+	if err := ticket.Wait(ctx); err != nil {
+		t.Errorf("expected waiting request to succeed, got: %v", err)
+	}
+	if lease.Context().Err() == nil {
+		t.Errorf("expected Lease context ended")
+	}
+	if err := lease.Commit(); !errors.Is(err, ErrLeaseReleased) {
+		t.Errorf("expected Commit of aborted Lease to return %v, got: %v", ErrLeaseReleased, err)
+	}
+	checkDb(t, db, &waiting)
+}
+
+func Test_LeaseLeaked(t *testing.T) {
+	logger := &errorLogger{}
+	arbiter, db, ctx, _, li, err := testSetup(SetLogger(logger))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	// A context which has not ended does not keep a leaked Lease from being collected.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	acquired := requestDefs["record1version9"]
+	setupTestItem(&acquired, db)
+
+	// Acquire and drop the Lease without releasing it.
+	func() {
+		if _, err := arbiter.Acquire(ctx, &acquired); err != nil {
+			t.Fatalf("expected Acquire to succeed, got: %v", err)
+		}
+	}()
+	waitForGauge(t, li, at.ProcessingMapDepth, 1)
+
+	deadline := time.Now().Add(time.Second)
+	for li.SnapMetrics().Gauges[at.ProcessingMapDepth] != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for leaked Lease to be aborted")
+		}
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if logger.count() != 1 {
+		t.Errorf("expected leaked Lease to be reported once, got %d reports", logger.count())
+	}
+	if got := db.get(acquired.key); got != 0 {
+		t.Errorf("expected leaked Lease not finalized, got store value %d", got)
+	}
+}
+
+// errorLogger counts messages logged at error level.
+type errorLogger struct {
+	logging.NoopLogger
+	mtx    sync.Mutex
+	errors int
+}
+
+func (l *errorLogger) Error(string, []logging.LogTuple) {
+	l.mtx.Lock()
+	l.errors++
+	l.mtx.Unlock()
+}
+
+func (l *errorLogger) count() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.errors
+}
@@ -1,53 +1,129 @@
 package internal
 
-import "github.com/btsomogyi/arbiter/interfaces"
+import (
+	"github.com/btsomogyi/arbiter/interfaces"
+)
 
-type messageMap struct {
-	msgMap map[int64]message
+// messageMap stores the messages holding each key.  A key is held by a single exclusive message,
+// or by any number of shared messages (see interfaces.Moded).  Messages reserving a key range
+// (see interfaces.RangeRequest) are stored in an interval index instead.
+type messageMap[K comparable] struct {
+	msgMap map[K][]message[K]
+	ranges intervals[K]
+	order  keyIndex[K]
 }
 
-func newMessageMap() *messageMap {
-	return &messageMap{
-		msgMap: make(map[int64]message),
+func newMessageMap[K comparable]() *messageMap[K] {
+	return &messageMap[K]{
+		msgMap: make(map[K][]message[K]),
 	}
 }
 
-// getMessage returns the message stored at index key.
-func (mm *messageMap) getMessage(key int64) (message, bool) {
-	m, ok := mm.msgMap[key]
-	return m, ok
+// getMessage returns the first message stored at index key.
+func (mm *messageMap[K]) getMessage(key K) (message[K], bool) {
+	if holders := mm.msgMap[key]; len(holders) > 0 {
+		return holders[0], true
+	}
+	return nil, false
+}
+
+// holders returns every message stored at index key.
+func (mm *messageMap[K]) holders(key K) []message[K] {
+	return mm.msgMap[key]
 }
 
-// findMessage determines if the exact message is stored in messageMap.
-func (mm *messageMap) containsMessage(m message) bool {
-	key := m.request().GetKey()
-	if message, found := mm.msgMap[key]; found {
-		return message.same(m)
+// find returns the stored message which is the exact message m.
+func (mm *messageMap[K]) find(m message[K]) (message[K], bool) {
+	if isRange(m) {
+		return mm.ranges.find(m)
 	}
-	return false
+	for _, message := range mm.msgMap[m.keyset().key] {
+		if message.same(m) {
+			return message, true
+		}
+	}
+	return nil, false
 }
 
-// remove removes the message stored at index key.
-func (mm *messageMap) remove(m message) {
-	key := m.request().GetKey()
-	delete(mm.msgMap, key)
+// messages returns every stored message once, including messages reserving a key range, in
+// arrival order.
+func (mm *messageMap[K]) messages() []message[K] {
+	var msgs []message[K]
+	seen := make(map[message[K]]struct{})
+	for _, holders := range mm.msgMap {
+		msgs = appendUnseen(msgs, seen, holders)
+	}
+	msgs = append(msgs, mm.ranges.messages()...)
+	sortByArrival(msgs)
+	return msgs
 }
 
-// add the message to the messageMap.
-func (mm *messageMap) add(m message) {
-	key := m.request().GetKey()
-	mm.msgMap[key] = m
+// containsMessage determines if the exact message is stored in messageMap.
+func (mm *messageMap[K]) containsMessage(m message[K]) bool {
+	_, found := mm.find(m)
+	return found
+}
+
+// remove removes the message stored at each of its keys, or its key range.
+func (mm *messageMap[K]) remove(m message[K]) {
+	if isRange(m) {
+		mm.ranges.remove(m)
+		return
+	}
+	for _, key := range m.keyset().keys {
+		holders := mm.msgMap[key]
+		for i, message := range holders {
+			if message.same(m) {
+				holders = append(holders[:i:i], holders[i+1:]...)
+				break
+			}
+		}
+		if len(holders) == 0 {
+			delete(mm.msgMap, key)
+			mm.order.remove(key)
+			continue
+		}
+		mm.msgMap[key] = holders
+	}
+}
+
+// add the message to the messageMap at each of its keys, or its key range.  Adding a message
+// already stored, such as one admitted after reserving its keys, has no effect.
+func (mm *messageMap[K]) add(m message[K]) {
+	if mm.containsMessage(m) {
+		return
+	}
+	if lo, hi, ok := m.keyset().span(); ok {
+		mm.ranges.insert(lo, hi, m)
+		return
+	}
+	for _, key := range m.keyset().keys {
+		if _, held := mm.msgMap[key]; !held {
+			mm.order.add(key)
+		}
+		mm.msgMap[key] = append(mm.msgMap[key], m)
+	}
+}
+
+// keysIn returns the held keys within [lo, hi), in key order.
+func (mm *messageMap[K]) keysIn(lo, hi K) []K {
+	return mm.order.in(mm.msgMap, lo, hi)
 }
 
 // length returns the number of entries in the messageMap.
-func (mm *messageMap) length() int {
-	return len(mm.msgMap)
+func (mm *messageMap[K]) length() int {
+	return len(mm.msgMap) + mm.ranges.length()
 }
 
 // Dump returns all key/values in messageMap for debugging purposes.
-func (mm *messageMap) dump() []interfaces.Request {
-	var dump []interfaces.Request
-	for _, v := range mm.msgMap {
+func (mm *messageMap[K]) dump() []interfaces.Request[K] {
+	var dump []interfaces.Request[K]
+	for _, holders := range mm.msgMap {
+		for _, v := range holders {
+			dump = append(dump, v.request())
+		}
+	}
+	for _, v := range mm.ranges.messages() {
 		dump = append(dump, v.request())
 	}
 	return dump
@@ -0,0 +1,22 @@
+package internal
+
+// merge coalesces begin message m with the newest message waiting for its key, where the waiting
+// request implements interfaces.Merger.  The merged request replaces the waiting request, and m
+// follows the waiting message, receiving the outcome of the merged request.  Returns false if
+// the requests were not merged, so m is enqueued as usual.
+func (s *Supervisor[K]) merge(m message[K]) bool {
+	waiting, ok := s.waiting.newest(m.keyset().key)
+	if !ok || waiting.signature().lease || m.signature().lease {
+		return false
+	}
+	merged, ks, ok := s.callMerge(waiting.request(), m.request())
+	if !ok || ks.key != waiting.keyset().key || len(ks.keys) > 1 || ks.ranged {
+		return false
+	}
+	waiting.signature().merged = merged
+	waiting.signature().keys = ks
+	m.setStatus(msMerged)
+	s.attach(m, waiting)
+	s.metrics.Coalesced()
+	return true
+}
@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+var errMerge = errors.New("merge error")
+
+// mergeReq is a testReq carrying partial updates, which are combined when merged.
+type mergeReq struct {
+	testReq
+	parts    []string
+	mergeErr error
+	applied  *[]string // parts of the request finalized.
+}
+
+func (p *mergeReq) Merge(o interfaces.Request[int64]) (interfaces.Request[int64], error) {
+	other, ok := o.(*mergeReq)
+	if !ok || p.mergeErr != nil {
+		return nil, errMerge
+	}
+	merged := *other
+	merged.parts = append(append([]string{}, p.parts...), other.parts...)
+	return &merged, nil
+}
+
+func (p *mergeReq) Finalize() error {
+	*p.applied = p.parts
+	return p.testReq.Finalize()
+}
+
+func Test_SupervisorMerge(t *testing.T) {
+	errWork := errors.New("work error")
+
+	type want struct {
+		errs   []error // errors the outcome error must match (via errors.Is)
+		merged bool
+	}
+	tests := map[string]struct {
+		reqs          []string // requests submitted in order, each with its name as its part
+		mergeErr      bool     // first request fails to merge
+		workErr       error
+		wantRun       []string // requests whose work function runs
+		wantApplied   []string
+		wantCoalesced int64
+		want          map[string]want
+	}{
+		"merged with waiting": {
+			reqs:          []string{"record1version10", "record1version11"},
+			wantRun:       []string{"record1version10"},
+			wantApplied:   []string{"record1version10", "record1version11"},
+			wantCoalesced: 1,
+			want: map[string]want{
+				"record1version10": {},
+				"record1version11": {merged: true},
+			},
+		},
+		"merged repeatedly": {
+			reqs:          []string{"record1version10", "record1version11", "record1version20"},
+			wantRun:       []string{"record1version10"},
+			wantApplied:   []string{"record1version10", "record1version11", "record1version20"},
+			wantCoalesced: 2,
+			want: map[string]want{
+				"record1version10": {},
+				"record1version11": {merged: true},
+				"record1version20": {merged: true},
+			},
+		},
+		"merge error displaces": {
+			reqs:        []string{"record1version10", "record1version11"},
+			mergeErr:    true,
+			wantRun:     []string{"record1version11"},
+			wantApplied: []string{"record1version11"},
+			want: map[string]want{
+				"record1version10": {errs: []error{ErrDisplaced}},
+				"record1version11": {},
+			},
+		},
+		"merged request failed": {
+			reqs:          []string{"record1version10", "record1version11"},
+			workErr:       errWork,
+			wantRun:       []string{"record1version10"},
+			wantCoalesced: 1,
+			want: map[string]want{
+				"record1version10": {errs: []error{ErrWorkFailed, errWork}},
+				"record1version11": {errs: []error{ErrLeaderFailed}, merged: true},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup()
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			held := requestDefs["record1version9"]
+			setupTestItem(&held, db)
+			lease, err := arbiter.Acquire(ctx, &held)
+			if err != nil {
+				t.Fatalf("expected Acquire to succeed, got: %v", err)
+			}
+
+			var mtx sync.Mutex
+			var ran, applied []string
+			tickets := make(map[string]*Ticket)
+			for i, name := range tc.reqs {
+				name := name
+				r := &mergeReq{testReq: requestDefs[name], parts: []string{name}, applied: &applied}
+				if i == 0 && tc.mergeErr {
+					r.mergeErr = errMerge
+				}
+				setupTestItem(&r.testReq, db)
+				tickets[name] = arbiter.Submit(ctx, r, func(context.Context) error {
+					mtx.Lock()
+					ran = append(ran, name)
+					mtx.Unlock()
+					return tc.workErr
+				})
+			}
+			// Begin messages are sent before Submit returns, so are processed ahead of the end message.
+			if err := lease.Commit(); err != nil {
+				t.Errorf("expected held request to succeed, got: %v", err)
+			}
+
+			for name, want := range tc.want {
+				<-tickets[name].Done()
+				got := tickets[name].outcome
+				for _, wantErr := range want.errs {
+					if !errors.Is(got.Err, wantErr) {
+						t.Errorf("%s: expected outcome error to match %v, got: %v", name, wantErr, got.Err)
+					}
+				}
+				if len(want.errs) == 0 && got.Err != nil {
+					t.Errorf("%s: expected no outcome error, got: %v", name, got.Err)
+				}
+				if got.Merged != want.merged || got.Followed != want.merged {
+					t.Errorf("%s: expected merged %v, got: %+v", name, want.merged, got)
+				}
+				if want.merged {
+					if leader, ok := got.Leader.(*mergeReq); !ok || len(leader.parts) != len(tc.reqs) {
+						t.Errorf("%s: expected to follow the merged request, got: %v", name, got.Leader)
+					}
+				}
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if len(ran) != len(tc.wantRun) || (len(ran) > 0 && ran[0] != tc.wantRun[0]) {
+				t.Errorf("expected work run for %v, got %v", tc.wantRun, ran)
+			}
+			if len(applied) != len(tc.wantApplied) {
+				t.Fatalf("expected parts %v finalized, got %v", tc.wantApplied, applied)
+			}
+			for i := range applied {
+				if applied[i] != tc.wantApplied[i] {
+					t.Errorf("expected parts %v finalized, got %v", tc.wantApplied, applied)
+					break
+				}
+			}
+			if got := li.SnapMetrics().Counters[at.Coalesced]; got != tc.wantCoalesced {
+				t.Errorf("expected %d requests coalesced, got %d", tc.wantCoalesced, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// modedReq is a testReq with a mode.
+type modedReq struct {
+	testReq
+	mode interfaces.Mode
+}
+
+func (p *modedReq) Mode() interfaces.Mode {
+	return p.mode
+}
+
+func Test_SupervisorModes(t *testing.T) {
+	type req struct {
+		name   string
+		value  int64
+		shared bool
+	}
+	tests := map[string]tableTest[req]{
+		"shared run concurrently": {
+			hold:    []req{{name: "held", value: 9, shared: true}},
+			reqs:    []req{{name: "shared", value: 10, shared: true}},
+			early:   []string{"shared"},
+			wantRun: []string{"shared"},
+		},
+		"shared never superseded": {
+			hold:    []req{{name: "held", value: 20, shared: true}},
+			reqs:    []req{{name: "shared", value: 10, shared: true}},
+			early:   []string{"shared"},
+			wantRun: []string{"shared"},
+		},
+		"exclusive waits for shared": {
+			hold:    []req{{name: "held1", value: 20, shared: true}, {name: "held2", value: 20, shared: true}},
+			reqs:    []req{{name: "exclusive", value: 10}},
+			waiting: 1,
+			wantRun: []string{"exclusive"},
+		},
+		"shared waits for exclusive": {
+			hold:    []req{{name: "held", value: 20}},
+			reqs:    []req{{name: "shared", value: 10, shared: true}},
+			waiting: 1,
+			wantRun: []string{"shared"},
+		},
+		"exclusive superseded by exclusive": {
+			hold:     []req{{name: "held", value: 20}},
+			reqs:     []req{{name: "exclusive", value: 10}},
+			wantErrs: map[string]error{"exclusive": ErrSuperseded},
+		},
+		"exclusive not starved by shared": {
+			hold: []req{{name: "held", value: 9, shared: true}},
+			reqs: []req{
+				{name: "exclusive", value: 10},
+				{name: "shared", value: 11, shared: true},
+			},
+			waiting: 2,
+			wantRun: []string{"exclusive", "shared"},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		mr := &modedReq{testReq: testReq{key: 1, value: r.value}}
+		if r.shared {
+			mr.mode = interfaces.Shared
+		}
+		return r.name, mr
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runTableTest(t, tc, newReq)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package internal
+
+import (
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// requestKeys returns the distinct keys held by request r: its own keys (see ownKeys), followed
+// by the ancestor keys on its path if r implements interfaces.HierarchicalRequest.
+func requestKeys[K comparable](r interfaces.Request[K]) []K {
+	return pathKeys(r, ownKeys(r))
+}
+
+// pathKeys returns the own keys of request r, followed by the distinct ancestor keys on its path
+// if r implements interfaces.HierarchicalRequest.
+func pathKeys[K comparable](r interfaces.Request[K], own []K) []K {
+	keys := append([]K(nil), own...)
+	hr, ok := r.(interfaces.HierarchicalRequest[K])
+	if !ok {
+		return keys
+	}
+	for _, k := range hr.GetPath() {
+		if !containsKey(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// ownKeys returns the distinct keys request r operates on: GetKey, followed by any further keys
+// returned by GetKeys if r implements interfaces.MultiKeyRequest.
+func ownKeys[K comparable](r interfaces.Request[K]) []K {
+	keys := []K{r.GetKey()}
+	mr, ok := r.(interfaces.MultiKeyRequest[K])
+	if !ok {
+		return keys
+	}
+	for _, k := range mr.GetKeys() {
+		if !containsKey(keys, k) {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+func containsKey[K comparable](keys []K, key K) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps reports whether any key is shared by the two key sets.
+func overlaps[K comparable](a, b []K) bool {
+	for _, k := range a {
+		if containsKey(b, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// sequence assigns begin message m its arrival order and mode at the supervisor, once.
+func (s *Supervisor[K]) sequence(m message[K]) {
+	if bm, ok := m.(*beginMessage[K]); ok && bm.seq == 0 {
+		s.seq++
+		bm.seq = s.seq
+		bm.mode = s.callMode(m.request())
+	}
+}
+
+// arrival returns the arrival order of begin message m at the supervisor.
+func arrival[K comparable](m message[K]) uint64 {
+	if bm, ok := m.(*beginMessage[K]); ok {
+		return bm.seq
+	}
+	return 0
+}
+
+// shared reports whether begin message m holds its keys in shared mode.
+func shared[K comparable](m message[K]) bool {
+	bm, ok := m.(*beginMessage[K])
+	return ok && bm.mode == interfaces.Shared
+}
+
+// enquePending arbitrates begin message m for a request holding several keys, holding its key
+// in shared mode, or under conflict scheduling.  An exclusive request must supersede each
+// conflicting exclusive in-flight request for its keys, and is granted every key at once, only
+// when none is held by an incompatible request.  Otherwise it waits in the conflict index,
+// reserving its keys against requests arriving later, so it is not starved.  As keys are only
+// ever granted together, requests holding overlapping keys cannot deadlock.
+func (s *Supervisor[K]) enquePending(m message[K], keys []K) {
+	var inProcess []message[K]
+	for _, k := range keys {
+		for _, p := range s.processing.holders(k) {
+			if !s.contends(m, p, k) || containsMessage(inProcess, p) {
+				continue
+			}
+			if err := s.callSupersedes(m.request(), p.request()); err != nil {
+				if s.follow(m, p, err) {
+					return
+				}
+				s.cease(m, wrapOutcome(ErrSuperseded, err))
+				return
+			}
+			inProcess = append(inProcess, p)
+		}
+	}
+	if !s.displacePending(m, keys) {
+		return
+	}
+
+	if len(inProcess) == 0 && s.keysGrantable(m, keys) {
+		s.activateMessage(m)
+		return
+	}
+	s.pending.add(m)
+	s.waitlist(m)
+	for _, p := range inProcess {
+		s.preempt(p, m)
+	}
+}
+
+// keysGrantable reports whether the keys of message m can be granted: no key is held in the
+// processing map by an incompatible message, no message which arrived earlier is waiting for a
+// key, and no incompatible pending message which arrived earlier holds any of the keys.  Shared
+// messages are therefore not granted ahead of an exclusive message which arrived earlier, so
+// exclusive messages are not starved by a stream of shared messages.
+func (s *Supervisor[K]) keysGrantable(m message[K], keys []K) bool {
+	for _, k := range keys {
+		for _, h := range s.processing.holders(k) {
+			if !s.compatible(m, h, k) {
+				return false
+			}
+		}
+		for _, w := range s.waiting.queues[k] {
+			if arrival(w) < arrival(m) {
+				return false
+			}
+		}
+		for _, e := range s.pending.bucket(k) {
+			if arrival(e) >= arrival(m) {
+				break
+			}
+			if !s.compatible(m, e, k) {
+				return false
+			}
+		}
+	}
+	return !s.rangeBlocks(m, keys)
+}
+
+// reserved reports whether key is reserved for exclusive message m by a pending message which
+// arrived before m, including a pending message reserving a key range containing key.
+func (s *Supervisor[K]) reserved(key K, m message[K]) bool {
+	bucket := s.pending.bucket(key)
+	if len(bucket) > 0 && arrival(bucket[0]) < arrival(m) {
+		return true
+	}
+	if s.less == nil {
+		return false
+	}
+	for _, p := range s.pending.ranges.containing(key) {
+		if arrival(p) < arrival(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// promotePending activates pending messages holding any of keys, or reserving a key range
+// containing any of keys, in arrival order, where grantable.
+func (s *Supervisor[K]) promotePending(keys []K) {
+	candidates := s.pending.candidates(keys)
+	if s.pending.ranges.length() > 0 {
+		seen := make(map[message[K]]struct{})
+		for _, k := range keys {
+			candidates = appendUnseen(candidates, seen, s.pending.ranges.containing(k))
+		}
+		sortByArrival(candidates)
+	}
+	s.promote(candidates)
+}
+
+// promote activates the pending candidates, in order, where still pending and grantable.
+func (s *Supervisor[K]) promote(candidates []message[K]) {
+	for _, m := range candidates {
+		if !s.pending.contains(m) || !s.grantable(m) {
+			continue
+		}
+		s.pending.remove(m)
+		s.metrics.DecWaitingMapDepth()
+		s.expiry.remove(m)
+		if s.revalidate {
+			if !s.revalidateHeld(m, promoteOp) {
+				s.releaseMessage(m)
+			}
+			continue
+		}
+		if !s.activateMessage(m) {
+			s.releaseMessage(m)
+		}
+	}
+}
+
+// grantable reports whether the keys, or key range, of pending message m can be granted.
+func (s *Supervisor[K]) grantable(m message[K]) bool {
+	if lo, hi, ok := m.keyset().span(); ok {
+		return s.rangeGrantable(m, lo, hi)
+	}
+	return s.keysGrantable(m, m.keyset().keys)
+}
+
+// removePending removes the exact message from the pending messages, returning the message stored,
+// or false if not found.
+func (s *Supervisor[K]) removePending(m message[K]) (message[K], bool) {
+	p, found := s.pending.find(m)
+	if found {
+		s.pending.remove(p)
+	}
+	return p, found
+}
+
+// release promotes waiting messages once the keys have been released: first pending messages
+// whose keys are all grantable, then the next waiting message for each key still free.
+func (s *Supervisor[K]) release(keys []K) {
+	s.promotePending(keys)
+	s.promoteKeys(keys)
+}
+
+// promoteKeys promotes the next waiting message for each key.
+func (s *Supervisor[K]) promoteKeys(keys []K) {
+	for _, k := range keys {
+		s.promoteFromWaiting(k)
+	}
+}
+
+func containsMessage[K comparable](msgs []message[K], m message[K]) bool {
+	for _, o := range msgs {
+		if o.same(m) {
+			return true
+		}
+	}
+	return false
+}
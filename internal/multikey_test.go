@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// multiReq is a testReq holding further keys.
+type multiReq struct {
+	testReq
+	keys []int64
+}
+
+func (p *multiReq) GetKeys() []int64 {
+	return p.keys
+}
+
+func Test_requestKeys(t *testing.T) {
+	tests := map[string]struct {
+		r    interfaces.Request[int64]
+		want []int64
+	}{
+		"single key": {
+			r:    &testReq{key: 1},
+			want: []int64{1},
+		},
+		"multiple keys": {
+			r:    &multiReq{testReq: testReq{key: 1}, keys: []int64{3, 2}},
+			want: []int64{1, 3, 2},
+		},
+		"duplicate keys": {
+			r:    &multiReq{testReq: testReq{key: 1}, keys: []int64{2, 1, 2}},
+			want: []int64{1, 2},
+		},
+		"hierarchical path": {
+			r:    &pathReq{testReq: testReq{key: 3}, path: []int64{1, 2, 3}},
+			want: []int64{3, 1, 2},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := requestKeys(tc.r)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected keys %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected keys %v, got %v", tc.want, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func Test_SupervisorMultiKey(t *testing.T) {
+	type req struct {
+		name  string
+		key   int64
+		value int64
+		keys  []int64 // further keys held
+	}
+	tests := map[string]tableTest[req]{
+		"granted with all keys free": {
+			reqs:    []req{{name: "multi", key: 1, value: 10, keys: []int64{2, 3}}},
+			wantRun: []string{"multi"},
+		},
+		"waits for every key": {
+			hold: []req{{name: "held", key: 2, value: 9}},
+			reqs: []req{
+				{name: "multi", key: 1, value: 10, keys: []int64{2}},
+				{name: "single", key: 1, value: 11},
+			},
+			waiting: 2,
+			release: []string{"held"},
+			wantRun: []string{"multi", "single"},
+		},
+		"superseded on one key": {
+			hold:     []req{{name: "held", key: 2, value: 20}},
+			reqs:     []req{{name: "multi", key: 1, value: 10, keys: []int64{2}}},
+			release:  []string{"held"},
+			wantErrs: map[string]error{"multi": ErrSuperseded},
+		},
+		"overlapping requests granted in arrival order": {
+			hold: []req{
+				{name: "held1", key: 1, value: 9},
+				{name: "held3", key: 3, value: 9},
+			},
+			reqs: []req{
+				{name: "multi12", key: 1, value: 10, keys: []int64{2}},
+				{name: "multi23", key: 3, value: 10, keys: []int64{2}},
+			},
+			waiting: 2,
+			release: []string{"held3", "held1"},
+			wantRun: []string{"multi12", "multi23"},
+		},
+		"canceled while waiting releases keys": {
+			hold: []req{{name: "held", key: 2, value: 9}},
+			reqs: []req{
+				{name: "multi", key: 1, value: 10, keys: []int64{2}},
+				{name: "single", key: 1, value: 11},
+			},
+			waiting:  2,
+			cancel:   "multi",
+			release:  []string{"held"},
+			wantRun:  []string{"single"},
+			wantErrs: map[string]error{"multi": ErrCanceled},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		return r.name, &multiReq{testReq: testReq{key: r.key, value: r.value}, keys: r.keys}
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runTableTest(t, tc, newReq)
+		})
+	}
+}
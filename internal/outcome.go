@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"github.com/btsomogyi/arbiter/interfaces"
+	"strings"
+	"time"
+)
+
+// Phase identifies the stage of the worker protocol at which a request completed.
+type Phase string
+
+// Set of Phase values reported in an Outcome.
+const (
+	PhaseBegin Phase = "begin" // ceased, or canceled, before proceeding.
+	PhaseWork  Phase = "work"  // work function returned an error (or panicked), so no Finalize.
+	PhaseEnd   Phase = "end"   // end message responded, after Finalize when work succeeded.
+)
+
+// Outcome describes how a request submitted with WithWorkerOutcome completed.
+type Outcome struct {
+	Phase          Phase
+	Signal         string        // final signal received by the worker ("cease", "success" or "failure").
+	Waitlisted     bool          // request waited behind an in-flight request for its key.
+	Preempted      bool          // in-flight request was preempted by a superseding request.
+	Followed       bool          // request followed a superseding request (see SetFollowMode).
+	Leader         interface{}   // request followed, whose outcome was received, if Followed.
+	Merged         bool          // request was merged into a waiting request (see interfaces.Merger).
+	FinalizeFailed bool          // Finalize returned an error.
+	QueueWait      time.Duration // time from begin until the request proceeded or ceased.
+	WorkTime       time.Duration // time spent in the work function.
+	FinalizeTime   time.Duration // time from end until the end response, including Finalize.
+	Err            error         // error returned by WithWorker.
+}
+
+// record updates the outcome from a supervisor response, and the message status it carries.
+func (o *Outcome) record(r response) {
+	o.Signal = r.sig.outcome()
+	o.Err = r.err
+	if r.status&msWaitlist != 0 {
+		o.Waitlisted = true
+	}
+	if r.status&msFinalizeFailure != 0 {
+		o.FinalizeFailed = true
+	}
+	if r.status&msMerged != 0 {
+		o.Merged = true
+	}
+	if r.status&msFollowed != 0 {
+		o.Followed = true
+		o.Leader = r.leader
+	}
+}
+
+// outcome returns the signal name reported in an Outcome.
+func (s signal) outcome() string {
+	return strings.TrimSuffix(s.String(), "Signal")
+}
+
+// WithWorkerOutcome arbitrates request r as WithWorker, returning the Outcome of the request
+// rather than only its error.
+func (s *Supervisor[K]) WithWorkerOutcome(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) Outcome {
+	t := s.Submit(ctx, r, fn)
+	<-t.done
+	return t.outcome
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_WithWorkerOutcome(t *testing.T) {
+	errWork := errors.New("work error")
+	errFinalize := errors.New("finalize error")
+
+	tests := map[string]struct {
+		hold        string // request holding the key via a Lease while the request is submitted (optional)
+		waiting     string // request waiting on the held key before the request is submitted (optional)
+		current     string // request already stored before submission (optional)
+		req         string
+		cancel      bool // cancel the request once waiting
+		workErr     error
+		finalizeErr error
+		want        Outcome
+		wantErrs    []error // errors the outcome error must match (via errors.Is)
+		wantWaiting error   // expected error of the waiting request (if any)
+	}{
+		"success": {
+			req:  "record1version9",
+			want: Outcome{Phase: PhaseEnd, Signal: "success"},
+		},
+		"waitlisted success": {
+			hold: "record1version9",
+			req:  "record1version10",
+			want: Outcome{Phase: PhaseEnd, Signal: "success", Waitlisted: true},
+		},
+		"invalid": {
+			current:  "record1version10",
+			req:      "record1version9",
+			want:     Outcome{Phase: PhaseBegin, Signal: "cease"},
+			wantErrs: []error{ErrInvalid, ErrInvalidRequest},
+		},
+		"superseded by in-flight": {
+			hold:     "record1version10",
+			req:      "record1version9",
+			want:     Outcome{Phase: PhaseBegin, Signal: "cease"},
+			wantErrs: []error{ErrSuperseded, ErrSupersededRequest},
+		},
+		"displaced from waiting": {
+			hold:        "record1version9",
+			waiting:     "record1version10",
+			req:         "record1version11",
+			want:        Outcome{Phase: PhaseEnd, Signal: "success", Waitlisted: true},
+			wantWaiting: ErrDisplaced,
+		},
+		"canceled while waiting": {
+			hold:     "record1version9",
+			req:      "record1version10",
+			cancel:   true,
+			want:     Outcome{Phase: PhaseBegin, Signal: "cease"},
+			wantErrs: []error{ErrCanceled, context.Canceled},
+		},
+		"work failed": {
+			req:      "record1version9",
+			workErr:  errWork,
+			want:     Outcome{Phase: PhaseWork, Signal: "failure"},
+			wantErrs: []error{ErrWorkFailed, errWork},
+		},
+		"finalize failed": {
+			req:         "record1version9",
+			finalizeErr: errFinalize,
+			want:        Outcome{Phase: PhaseEnd, Signal: "failure", FinalizeFailed: true},
+			wantErrs:    []error{ErrFinalizeFailed, errFinalize},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup()
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+			if tc.current != "" {
+				db.init()
+				db.set(requestDefs[tc.current].key, requestDefs[tc.current].value)
+			}
+
+			var lease *Lease[int64]
+			if tc.hold != "" {
+				held := requestDefs[tc.hold]
+				setupTestItem(&held, db)
+				if lease, err = arbiter.Acquire(ctx, &held); err != nil {
+					t.Fatalf("expected Acquire to succeed, got: %v", err)
+				}
+			}
+			var waiting *Ticket
+			if tc.waiting != "" {
+				r := requestDefs[tc.waiting]
+				setupTestItem(&r, db)
+				waiting = arbiter.Submit(ctx, &r, func(context.Context) error { return nil })
+				waitForGauge(t, li, at.WaitingMapDepth, 1)
+			}
+
+			r := requestDefs[tc.req]
+			setupTestItem(&r, db)
+			if tc.finalizeErr != nil {
+				r.finalize = func() error { return tc.finalizeErr }
+			}
+			reqCtx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			done := make(chan Outcome)
+			go func() {
+				done <- arbiter.WithWorkerOutcome(reqCtx, &r, func(context.Context) error { return tc.workErr })
+			}()
+			if tc.cancel {
+				waitForGauge(t, li, at.WaitingMapDepth, 1)
+				cancel()
+			}
+			if waiting != nil {
+				if err := waiting.Wait(ctx); !errors.Is(err, tc.wantWaiting) {
+					t.Errorf("expected waiting request error %v, got: %v", tc.wantWaiting, err)
+				}
+			}
+			// Requests ending at begin complete before the held key is released, and waitlisted
+			// requests complete after.
+			var got Outcome
+			if tc.want.Phase == PhaseBegin {
+				got = <-done
+			} else if lease != nil {
+				waitForGauge(t, li, at.WaitingMapDepth, 1)
+			}
+			if lease != nil {
+				if err := lease.Commit(); err != nil {
+					t.Errorf("expected held request to succeed, got: %v", err)
+				}
+			}
+			if tc.want.Phase != PhaseBegin {
+				got = <-done
+			}
+
+			for _, want := range tc.wantErrs {
+				if !errors.Is(got.Err, want) {
+					t.Errorf("expected outcome error to match %v, got: %v", want, got.Err)
+				}
+			}
+			if len(tc.wantErrs) == 0 && got.Err != nil {
+				t.Errorf("expected no outcome error, got: %v", got.Err)
+			}
+			if got.Phase != tc.want.Phase || got.Signal != tc.want.Signal || got.Waitlisted != tc.want.Waitlisted ||
+				got.FinalizeFailed != tc.want.FinalizeFailed || got.Preempted != tc.want.Preempted {
+				t.Errorf("expected outcome %+v, got: %+v", tc.want, got)
+			}
+			if got.Phase != PhaseBegin && got.WorkTime <= 0 {
+				t.Errorf("expected work time recorded, got: %v", got.WorkTime)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"github.com/btsomogyi/arbiter/interfaces"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/btsomogyi/arbiter/logging"
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// PanicError is returned to the worker whose request method (Valid, Supersedes, Finalize, or any
+// optional method such as GetKeys) or work function panicked.  The panic is recovered, so the
+// Supervisor continues arbitrating.  Panics in the key methods (GetKey, GetKeys, GetPath and
+// GetRange), which are called once before the request is sent to the Supervisor, are reported
+// as GetKey, and the request is rejected with ErrInvalid.
+type PanicError struct {
+	Callback string      // request method or work function which panicked
+	Value    interface{} // value passed to panic
+	Stack    []byte      // stack trace of the panicking goroutine
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", e.Callback, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, ok := e.Value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+// guard invokes consumer callback f of request r, recovering any panic as a PanicError, which is
+// logged and counted.
+func (s *Supervisor[K]) guard(callback string, r interfaces.Request[K], f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack := make([]byte, 64<<10)
+			pe := &PanicError{
+				Callback: callback,
+				Value:    v,
+				Stack:    stack[:runtime.Stack(stack, false)],
+			}
+			s.logger.Error("Recovered panic in request callback", []logging.LogTuple{
+				{Field: "key", Value: loggedKey(r)},
+				{Field: "callback", Value: callback},
+				{Field: "panic", Value: fmt.Sprint(v)},
+				{Field: "stack", Value: string(pe.Stack)},
+			})
+			s.metrics.Panics(telemetry.Labels{
+				"callback": callback,
+			})
+			err = pe
+		}
+	}()
+	return f()
+}
+
+// loggedKey returns the key of request r for logging, or nil if GetKey panics.
+func loggedKey[K comparable](r interfaces.Request[K]) (key interface{}) {
+	defer func() {
+		if recover() != nil {
+			key = nil
+		}
+	}()
+	return r.GetKey()
+}
+
+// callValid invokes r.Valid, recovering any panic.
+func (s *Supervisor[K]) callValid(r interfaces.Request[K]) error {
+	return s.guard("Valid", r, r.Valid)
+}
+
+// callSupersedes invokes r.Supersedes(o), recovering any panic.
+func (s *Supervisor[K]) callSupersedes(r, o interfaces.Request[K]) error {
+	return s.guard("Supersedes", r, func() error {
+		return r.Supersedes(o)
+	})
+}
+
+// callCost returns the rate limit cost of request r, which is one token unless r implements
+// interfaces.Weighted.  A panic in Cost is recovered, and the request costs one token.
+func (s *Supervisor[K]) callCost(r interfaces.Request[K]) float64 {
+	w, ok := r.(interfaces.Weighted)
+	if !ok {
+		return 1
+	}
+	cost := 1.0
+	_ = s.guard("Cost", r, func() error {
+		cost = math.Max(0, w.Cost())
+		return nil
+	})
+	return cost
+}
+
+// callPriority returns the priority of request r, which is zero unless r implements
+// interfaces.Prioritized.  A panic in Priority is recovered, and the request has priority zero.
+func (s *Supervisor[K]) callPriority(r interfaces.Request[K]) int {
+	p, ok := r.(interfaces.Prioritized)
+	if !ok {
+		return 0
+	}
+	var priority int
+	_ = s.guard("Priority", r, func() error {
+		priority = p.Priority()
+		return nil
+	})
+	return priority
+}
+
+// callMode returns the mode of request r, which is Exclusive unless r implements
+// interfaces.Moded.  A panic in Mode is recovered, and the request is Exclusive.
+func (s *Supervisor[K]) callMode(r interfaces.Request[K]) interfaces.Mode {
+	m, ok := r.(interfaces.Moded)
+	if !ok {
+		return interfaces.Exclusive
+	}
+	mode := interfaces.Exclusive
+	_ = s.guard("Mode", r, func() error {
+		mode = m.Mode()
+		return nil
+	})
+	return mode
+}
+
+// callDependsOn returns the keys request r depends on, which are none unless r implements
+// interfaces.DependentRequest.  A panic in DependsOn is recovered, and the request has no
+// dependencies.
+func (s *Supervisor[K]) callDependsOn(r interfaces.Request[K]) []K {
+	dr, ok := r.(interfaces.DependentRequest[K])
+	if !ok {
+		return nil
+	}
+	var deps []K
+	_ = s.guard("DependsOn", r, func() error {
+		deps = dr.DependsOn()
+		return nil
+	})
+	return deps
+}
+
+// callExpires returns the expiry of request r, which is the zero time unless r implements
+// interfaces.Expiring.  A panic in Expires is recovered, and the request does not expire.
+func (s *Supervisor[K]) callExpires(r interfaces.Request[K]) time.Time {
+	e, ok := r.(interfaces.Expiring)
+	if !ok {
+		return time.Time{}
+	}
+	var expires time.Time
+	_ = s.guard("Expires", r, func() error {
+		expires = e.Expires()
+		return nil
+	})
+	return expires
+}
+
+// callWorkTimeout returns the maximum work duration of request r, which is zero unless r
+// implements interfaces.Timed.  A panic in WorkTimeout is recovered, and the request uses the
+// supervisor default.
+func (s *Supervisor[K]) callWorkTimeout(r interfaces.Request[K]) time.Duration {
+	t, ok := r.(interfaces.Timed)
+	if !ok {
+		return 0
+	}
+	var d time.Duration
+	_ = s.guard("WorkTimeout", r, func() error {
+		d = t.WorkTimeout()
+		return nil
+	})
+	return d
+}
+
+// callConflicts reports whether requests r and o conflict, which is the case unless each
+// implements interfaces.Conflicter and reports no conflict with the other.  A panic in
+// ConflictsWith is recovered, and the requests conflict.
+func (s *Supervisor[K]) callConflicts(r, o interfaces.Request[K]) bool {
+	return s.conflictsWith(r, o) || s.conflictsWith(o, r)
+}
+
+func (s *Supervisor[K]) conflictsWith(r, o interfaces.Request[K]) bool {
+	c, ok := r.(interfaces.Conflicter[K])
+	if !ok {
+		return true
+	}
+	conflicts := true
+	_ = s.guard("ConflictsWith", r, func() error {
+		conflicts = c.ConflictsWith(o)
+		return nil
+	})
+	return conflicts
+}
+
+// callMerge returns the result of r.Merge(o) and its keys, or false if r does not implement
+// interfaces.Merger, or Merge returned an error.  A panic in Merge, or in the key methods of the
+// merged request, is recovered, and the requests are not merged.
+func (s *Supervisor[K]) callMerge(r, o interfaces.Request[K]) (interfaces.Request[K], *keyset[K], bool) {
+	mr, ok := r.(interfaces.Merger[K])
+	if !ok {
+		return nil, nil, false
+	}
+	var merged interfaces.Request[K]
+	err := s.guard("Merge", r, func() (err error) {
+		merged, err = mr.Merge(o)
+		return err
+	})
+	if err != nil || merged == nil {
+		return nil, nil, false
+	}
+	ks, err := s.resolveKeys(merged)
+	return merged, ks, err == nil
+}
+
+// callFinalize invokes r.Finalize, recovering any panic.
+func (s *Supervisor[K]) callFinalize(r interfaces.Request[K]) error {
+	return s.guard("Finalize", r, r.Finalize)
+}
+
+// callWork invokes the work function of request r, recovering any panic.
+func (s *Supervisor[K]) callWork(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) error {
+	return s.guard("work", r, func() error {
+		return fn(ctx)
+	})
+}
@@ -0,0 +1,134 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// panicReq panics in the named request method.
+type panicReq struct {
+	testReq
+	method string
+}
+
+func (p *panicReq) GetKey() int64 {
+	if p.method == "GetKey" {
+		panic("GetKey panic")
+	}
+	return p.testReq.GetKey()
+}
+
+func (p *panicReq) GetKeys() []int64 {
+	if p.method == "GetKeys" {
+		panic("GetKeys panic")
+	}
+	return nil
+}
+
+func (p *panicReq) Valid() error {
+	if p.method == "Valid" {
+		panic("Valid panic")
+	}
+	return p.testReq.Valid()
+}
+
+func (p *panicReq) Supersedes(o interfaces.Request[int64]) error {
+	if p.method == "Supersedes" {
+		panic("Supersedes panic")
+	}
+	return p.testReq.Supersedes(o)
+}
+
+func (p *panicReq) Finalize() error {
+	if p.method == "Finalize" {
+		panic(errors.New("Finalize panic"))
+	}
+	return p.testReq.Finalize()
+}
+
+func Test_SupervisorPanics(t *testing.T) {
+	tests := map[string]struct {
+		callback string
+		reported string // callback reported by the PanicError, if not callback
+		inflight bool   // hold the key with an in-flight request, so Supersedes is called
+	}{
+		"GetKey":     {callback: "GetKey"},
+		"GetKeys":    {callback: "GetKeys", reported: "GetKey"},
+		"Valid":      {callback: "Valid"},
+		"Supersedes": {callback: "Supersedes", inflight: true},
+		"Finalize":   {callback: "Finalize"},
+		"work":       {callback: "work"},
+	}
+	modes := map[string][]SupervisorOption{
+		"inline":        nil,
+		"executor pool": {SetExecutorPool(2)},
+	}
+
+	for name, tc := range tests {
+		for mode, opts := range modes {
+			t.Run(name+" "+mode, func(t *testing.T) {
+				logger := &errorLogger{}
+				arbiter, db, ctx, _, li, err := testSetup(append(opts, SetLogger(logger))...)
+				if err != nil {
+					t.Fatalf("failed to setup test: %e", err)
+				}
+				go arbiter.Process()
+				defer arbiter.Terminate()
+
+				inflight := &panicReq{testReq: requestDefs["record1version9"]}
+				victim := &panicReq{testReq: requestDefs["record1version10"], method: tc.callback}
+				after := &panicReq{testReq: requestDefs["record1version11"]}
+				for _, r := range []*panicReq{inflight, victim, after} {
+					setupTestItem(&r.testReq, db)
+				}
+
+				var lease *Lease[int64]
+				if tc.inflight {
+					if lease, err = arbiter.Acquire(ctx, inflight); err != nil {
+						t.Fatalf("expected Acquire to succeed, got: %v", err)
+					}
+				}
+
+				err = arbiter.WithWorker(ctx, victim, func(context.Context) error {
+					if tc.callback == "work" {
+						panic("work panic")
+					}
+					return nil
+				})
+				var pe *PanicError
+				if !errors.As(err, &pe) {
+					t.Fatalf("expected PanicError, got: %v", err)
+				}
+				reported := tc.callback
+				if tc.reported != "" {
+					reported = tc.reported
+				}
+				if pe.Callback != reported || len(pe.Stack) == 0 {
+					t.Errorf("expected PanicError from %s with stack, got: %q", reported, pe.Callback)
+				}
+				if got := li.SnapMetrics().Counters[at.Panics]; got != 1 {
+					t.Errorf("expected 1 panic counted, got %d", got)
+				}
+				if got := logger.count(); got != 1 {
+					t.Errorf("expected 1 panic logged, got %d", got)
+				}
+
+				// The key is released once the panicking request completes.
+				if lease != nil {
+					if err := lease.Commit(); err != nil {
+						t.Errorf("expected in-flight request to succeed, got: %v", err)
+					}
+				}
+				if err := arbiter.WithWorker(ctx, after, func(context.Context) error { return nil }); err != nil {
+					t.Errorf("expected request after panic to succeed, got: %v", err)
+				}
+				waitForGauge(t, li, at.ProcessingMapDepth, 0)
+				checkDb(t, db, &after.testReq)
+			})
+		}
+	}
+}
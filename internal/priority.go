@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	"github.com/btsomogyi/arbiter/logging"
+)
+
+// SetPriorityLevels enables a multi-level queue in front of the supervisor loop, so begin messages
+// of requests implementing interfaces.Prioritized are processed ahead of lower priority messages
+// already queued.  Priorities are clamped to levels 0 (lowest, and the priority of requests not
+// implementing Prioritized) through n-1.  Zero (the default) processes messages in arrival order.
+// Messages waiting for a key are ordered by the waiting policy instead, which only considers
+// priority under FIFO and SupersedesOrder (see WaitingPolicy).
+func SetPriorityLevels(n uint) SupervisorOption {
+	return func(c *config) error {
+		c.priorityLevels = n
+		return nil
+	}
+}
+
+// SetPriorityAging raises the effective priority of a queued begin message by one level for each
+// interval d it has been queued, so lower priority messages are not starved.  Zero (the default)
+// disables aging.
+func SetPriorityAging(d time.Duration) SupervisorOption {
+	return func(c *config) error {
+		c.priorityAging = d
+		return nil
+	}
+}
+
+// closedReady is always ready to receive, signaling buffered messages are available.
+var closedReady = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// priorityEntry is a begin message buffered in the priority queue.
+type priorityEntry[K comparable] struct {
+	m      message[K]
+	queued time.Time
+}
+
+// priorityQueue buffers begin messages received from the queue channel, releasing them from the
+// highest effective priority level.  Each level is ordered by arrival.  End and completion
+// messages are never buffered.  At most limit begin messages are buffered, so the queue channel
+// continues to apply backpressure to workers once the buffer is full.
+type priorityQueue[K comparable] struct {
+	levels [][]priorityEntry[K]
+	aging  time.Duration
+	count  int
+	limit  int
+}
+
+// newPriorityQueue returns a priority queue with the number of levels, buffering at most limit
+// begin messages, or nil if disabled.
+func newPriorityQueue[K comparable](levels uint, aging time.Duration, limit int) *priorityQueue[K] {
+	if levels == 0 {
+		return nil
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	return &priorityQueue[K]{
+		levels: make([][]priorityEntry[K], levels),
+		aging:  aging,
+		limit:  limit,
+	}
+}
+
+// full reports whether the priority queue has buffered its limit of begin messages.
+func (pq *priorityQueue[K]) full() bool {
+	return pq.count >= pq.limit
+}
+
+// push adds begin message m at the priority level (clamped to the defined levels).
+func (pq *priorityQueue[K]) push(m message[K], level int, now time.Time) {
+	pq.count++
+	if level < 0 {
+		level = 0
+	}
+	if level >= len(pq.levels) {
+		level = len(pq.levels) - 1
+	}
+	pq.levels[level] = append(pq.levels[level], priorityEntry[K]{m: m, queued: now})
+}
+
+// pop removes and returns the next begin message, or nil if empty.  The head of the level with
+// the highest effective priority (including aging) is returned, with ties going to the earliest
+// queued.
+func (pq *priorityQueue[K]) pop(now time.Time) message[K] {
+	best, bestLevel := -1, 0
+	for level := len(pq.levels) - 1; level >= 0; level-- {
+		if len(pq.levels[level]) == 0 {
+			continue
+		}
+		head := pq.levels[level][0]
+		effective := level
+		if pq.aging > 0 {
+			effective += int(now.Sub(head.queued) / pq.aging)
+		}
+		if best < 0 || effective > bestLevel ||
+			(effective == bestLevel && head.queued.Before(pq.levels[best][0].queued)) {
+			best, bestLevel = level, effective
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	m := pq.levels[best][0].m
+	pq.levels[best][0] = priorityEntry[K]{}
+	pq.levels[best] = pq.levels[best][1:]
+	pq.count--
+	return m
+}
+
+// remove removes the buffered begin message of the worker of message m, returning false if none
+// is buffered.
+func (pq *priorityQueue[K]) remove(m message[K]) bool {
+	for level, entries := range pq.levels {
+		for i, e := range entries {
+			if e.m.same(m) {
+				pq.levels[level] = append(entries[:i:i], entries[i+1:]...)
+				pq.count--
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ready returns a channel ready to receive while messages are buffered, or nil if empty.
+func (pq *priorityQueue[K]) ready() <-chan struct{} {
+	if pq == nil || pq.count == 0 {
+		return nil
+	}
+	return closedReady
+}
+
+// prioritize returns the next message to process, or nil if none.  With priority levels enabled,
+// begin message m (if not nil) and begin messages already in the queue channel are buffered in
+// the priority queue, up to its limit, and the highest priority begin message is returned.  End
+// and completion messages are returned as received, once any buffered begin message of the same
+// worker is discarded, since the worker has ended.  Otherwise m is returned unchanged.
+func (s *Supervisor[K]) prioritize(m message[K]) message[K] {
+	if s.priority == nil {
+		return m
+	}
+	now := time.Now()
+	if m != nil {
+		if next, ok := s.buffer(m, now); !ok {
+			return next
+		}
+	}
+	for !s.priority.full() {
+		select {
+		case m := <-s.queue:
+			s.metrics.DecQueueChanDepth()
+			if next, ok := s.buffer(m, now); !ok {
+				return next
+			}
+		default:
+			return s.priority.pop(now)
+		}
+	}
+	return s.priority.pop(now)
+}
+
+// buffer adds begin message m to the priority queue, returning false with any other message, which
+// is processed as received.
+func (s *Supervisor[K]) buffer(m message[K], now time.Time) (message[K], bool) {
+	if _, ok := m.(*beginMessage[K]); !ok {
+		if _, ok := m.(*endMessage[K]); ok && s.priority.remove(m) {
+			s.logger.Debug("Supervisor discarded begin message of ended worker", []logging.LogTuple{
+				{Field: "key", Value: m.keyset().key},
+			})
+		}
+		return m, false
+	}
+	s.priority.push(m, s.callPriority(m.request()), now)
+	return nil, true
+}
+
+// priorityLabel returns the priority of request r for metric labels.
+func (s *Supervisor[K]) priorityLabel(r interfaces.Request[K]) string {
+	return strconv.Itoa(s.callPriority(r))
+}
@@ -0,0 +1,289 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// prioritizedReq is a testReq with a priority.
+type prioritizedReq struct {
+	testReq
+	priority int
+}
+
+func (p *prioritizedReq) Priority() int {
+	return p.priority
+}
+
+func Test_priorityQueue(t *testing.T) {
+	type push struct {
+		name  string
+		level int
+		ended bool          // the worker ends once pushed, removing its begin message
+		at    time.Duration // offset from start
+	}
+	tests := map[string]struct {
+		aging    time.Duration
+		limit    int
+		pushes   []push
+		popAt    time.Duration
+		want     []string
+		wantFull bool
+	}{
+		"highest level first, arrival order within level": {
+			pushes: []push{
+				{name: "low1", level: 0},
+				{name: "high1", level: 2},
+				{name: "mid", level: 1},
+				{name: "high2", level: 2},
+				{name: "low2", level: 0},
+			},
+			want: []string{"high1", "high2", "mid", "low1", "low2"},
+		},
+		"levels clamped": {
+			pushes: []push{
+				{name: "negative", level: -1},
+				{name: "low", level: 0},
+				{name: "over", level: 10},
+			},
+			want: []string{"over", "negative", "low"},
+		},
+		"ended worker removed": {
+			pushes: []push{
+				{name: "high", level: 2, ended: true},
+				{name: "low", level: 0},
+			},
+			want: []string{"low"},
+		},
+		"full at limit": {
+			limit: 2,
+			pushes: []push{
+				{name: "low", level: 0},
+				{name: "high", level: 2},
+			},
+			want:     []string{"high", "low"},
+			wantFull: true,
+		},
+		"aged messages overtake": {
+			aging: 10 * time.Millisecond,
+			pushes: []push{
+				{name: "old", level: 0, at: 0},
+				{name: "mid", level: 1, at: 15 * time.Millisecond},
+				{name: "high", level: 2, at: 25 * time.Millisecond},
+			},
+			// Effective levels at 30ms: old 3, mid 2, high 2 (tie to the earlier queued).
+			popAt: 30 * time.Millisecond,
+			want:  []string{"old", "mid", "high"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			limit := tc.limit
+			if limit == 0 {
+				limit = len(tc.pushes) + 1
+			}
+			pq := newPriorityQueue[int64](3, tc.aging, limit)
+			start := time.Now()
+			names := make(map[message[int64]]string)
+			for i, p := range tc.pushes {
+				r := &testReq{key: int64(i)}
+				m := newTestBegin[int64](r, nil)
+				names[m] = p.name
+				pq.push(m, p.level, start.Add(p.at))
+				if p.ended && !pq.remove(newTestEnd(m, failureSignal)) {
+					t.Errorf("expected begin message of ended worker %s removed", p.name)
+				}
+			}
+			if pq.full() != tc.wantFull {
+				t.Errorf("expected full %t, got %t", tc.wantFull, pq.full())
+			}
+			if pq.ready() == nil {
+				t.Errorf("expected priority queue ready")
+			}
+			var got []string
+			for m := pq.pop(start.Add(tc.popAt)); m != nil; m = pq.pop(start.Add(tc.popAt)) {
+				got = append(got, names[m])
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected %v, got %v", tc.want, got)
+					break
+				}
+			}
+			if pq.ready() != nil {
+				t.Errorf("expected empty priority queue not ready")
+			}
+		})
+	}
+}
+
+func Test_SupervisorPriority(t *testing.T) {
+	arbiter, db, ctx, _, _, err := testSetup(SetPriorityLevels(3))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	defer arbiter.Terminate()
+
+	// Valid is called on the supervisor goroutine, so records the order begin messages are processed.
+	var order []int64
+	priorities := []int{0, 2, 1, 2}
+	var tickets []*Ticket
+	for i, p := range priorities {
+		r := &prioritizedReq{testReq: testReq{key: int64(i + 1), value: 1}, priority: p}
+		setupTestItem(&r.testReq, db)
+		valid := r.valid
+		r.valid = func() error {
+			order = append(order, r.key)
+			return valid()
+		}
+		tickets = append(tickets, arbiter.Submit(ctx, r, func(context.Context) error { return nil }))
+	}
+
+	// Requests queued before processing begins are handled in priority order.
+	go arbiter.Process()
+	for _, ticket := range tickets {
+		if err := ticket.Wait(ctx); err != nil {
+			t.Errorf("expected request to succeed, got: %v", err)
+		}
+	}
+	want := []int64{2, 4, 3, 1}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Errorf("expected keys processed in order %v, got %v", want, order)
+			break
+		}
+	}
+}
+
+func Test_SupervisorPriorityCanceled(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup(SetPriorityLevels(3))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	defer arbiter.Terminate()
+
+	canceled := requestDefs["record1version9"]
+	next := requestDefs["record1version10"]
+	for _, r := range []*testReq{&canceled, &next} {
+		setupTestItem(r, db)
+	}
+
+	// The worker ends while its begin message is queued, so its end message is queued behind it.
+	ticket := arbiter.Submit(ctx, &canceled, func(context.Context) error { return nil })
+	ticket.Cancel()
+	<-ticket.Done()
+
+	// The begin message of the ended worker is discarded, rather than holding its key.
+	go arbiter.Process()
+	if err := arbiter.WithWorker(ctx, &next, func(context.Context) error { return nil }); err != nil {
+		t.Errorf("expected request after canceled request to succeed, got: %v", err)
+	}
+	waitForGauge(t, li, at.ProcessingMapDepth, 0)
+	checkDb(t, db, &next)
+}
+
+func Test_WaitingPriority(t *testing.T) {
+	tests := map[string]struct {
+		policy  WaitingPolicy
+		waiting []*prioritizedReq
+		want    []int64 // values finalized, in order
+	}{
+		"FIFO promotes higher priority first": {
+			policy: FIFO,
+			waiting: []*prioritizedReq{
+				{testReq: requestDefs["record1version10"]},
+				{testReq: requestDefs["record1version11"], priority: 1},
+			},
+			want: []int64{9, 11, 10},
+		},
+		"NewestWins promotes in arrival order": {
+			policy: NewestWins,
+			waiting: []*prioritizedReq{
+				{testReq: requestDefs["record1version10"], priority: 1},
+				{testReq: requestDefs["record1version11"]},
+			},
+			want: []int64{9, 10, 11},
+		},
+		"NewestWins ceases higher priority not superseding": {
+			policy: NewestWins,
+			waiting: []*prioritizedReq{
+				{testReq: requestDefs["record1version11"]},
+				{testReq: requestDefs["record1version10"], priority: 1},
+			},
+			want: []int64{9, 11},
+		},
+		"SupersedesOrder tie retains higher priority": {
+			policy: SupersedesOrder,
+			waiting: []*prioritizedReq{
+				{testReq: requestDefs["record1version10"]},
+				{testReq: testReq{key: 1, value: 10}, priority: 1},
+			},
+			want: []int64{9, 10},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup(SetWaitingPolicy(tc.policy), SetWaitingDepth(3))
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			// Finalize is called on the supervisor goroutine, so records the order of completion.
+			var mtx sync.Mutex
+			var finalized []int64
+			record := func(r *prioritizedReq) {
+				setupTestItem(&r.testReq, db)
+				finalize := r.finalize
+				r.finalize = func() error {
+					mtx.Lock()
+					finalized = append(finalized, r.value)
+					mtx.Unlock()
+					return finalize()
+				}
+			}
+
+			inflight := &prioritizedReq{testReq: requestDefs["record1version9"]}
+			record(inflight)
+			lease, err := arbiter.Acquire(ctx, inflight)
+			if err != nil {
+				t.Fatalf("expected Acquire to succeed, got: %v", err)
+			}
+			var tickets []*Ticket
+			for _, r := range tc.waiting {
+				record(r)
+				tickets = append(tickets, arbiter.Submit(ctx, r, func(context.Context) error { return nil }))
+			}
+			// Begin messages are sent before Submit returns, so are processed ahead of the end message.
+			waitForGauge(t, li, at.WaitingMapDepth, int64(len(tc.want)-1))
+			if err := lease.Commit(); err != nil {
+				t.Errorf("expected in-flight request to succeed, got: %v", err)
+			}
+			for _, ticket := range tickets {
+				<-ticket.Done()
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if len(finalized) != len(tc.want) {
+				t.Fatalf("expected finalized %v, got %v", tc.want, finalized)
+			}
+			for i := range tc.want {
+				if finalized[i] != tc.want[i] {
+					t.Errorf("expected finalized %v, got %v", tc.want, finalized)
+					break
+				}
+			}
+		})
+	}
+}
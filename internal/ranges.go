@@ -0,0 +1,419 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// SetKeyOrder sets the order of request keys used to arbitrate key ranges (see
+// interfaces.RangeRequest), where less reports whether key a orders before key b.  The key type K
+// must match that of the Supervisor.  If not provided, keys of integer, floating point and string
+// kinds are ordered naturally, and range requests for other key types are ceased with
+// ErrKeyRange.
+func SetKeyOrder[K comparable](less func(a, b K) bool) SupervisorOption {
+	return func(c *config) error {
+		if less == nil {
+			return fmt.Errorf("key order function must not be nil")
+		}
+		c.keyOrder = less
+		return nil
+	}
+}
+
+// keyOrder returns the key order configured for key type K, or the natural order of K if not
+// configured (nil where K has none).
+func keyOrder[K comparable](c *config) (func(a, b K) bool, error) {
+	if c.keyOrder == nil {
+		return naturalOrder[K](), nil
+	}
+	less, ok := c.keyOrder.(func(a, b K) bool)
+	if !ok {
+		return nil, fmt.Errorf("key order function %T does not match key type", c.keyOrder)
+	}
+	return less, nil
+}
+
+// naturalOrder returns the natural order of key type K for integer, floating point and string
+// kinds, or nil otherwise.  The kind of K is resolved once, so keys are compared without reflection.
+func naturalOrder[K comparable]() func(a, b K) bool {
+	switch reflect.TypeOf((*K)(nil)).Elem().Kind() {
+	case reflect.Int:
+		return orderAs[K, int]()
+	case reflect.Int8:
+		return orderAs[K, int8]()
+	case reflect.Int16:
+		return orderAs[K, int16]()
+	case reflect.Int32:
+		return orderAs[K, int32]()
+	case reflect.Int64:
+		return orderAs[K, int64]()
+	case reflect.Uint:
+		return orderAs[K, uint]()
+	case reflect.Uint8:
+		return orderAs[K, uint8]()
+	case reflect.Uint16:
+		return orderAs[K, uint16]()
+	case reflect.Uint32:
+		return orderAs[K, uint32]()
+	case reflect.Uint64:
+		return orderAs[K, uint64]()
+	case reflect.Uintptr:
+		return orderAs[K, uintptr]()
+	case reflect.Float32:
+		return orderAs[K, float32]()
+	case reflect.Float64:
+		return orderAs[K, float64]()
+	case reflect.String:
+		return orderAs[K, string]()
+	}
+	return nil
+}
+
+// ordered is the set of types with a natural order.
+type ordered interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~uintptr | ~float32 | ~float64 | ~string
+}
+
+// orderAs returns the natural order of key type K, whose underlying type is T.  Keys are read as
+// T, which shares the memory layout of K.
+func orderAs[K comparable, T ordered]() func(a, b K) bool {
+	return func(a, b K) bool {
+		return *(*T)(unsafe.Pointer(&a)) < *(*T)(unsafe.Pointer(&b))
+	}
+}
+
+// rangeOf returns the key range [lo, hi) reserved by request r, or false if r does not implement
+// interfaces.RangeRequest.
+func rangeOf[K comparable](r interfaces.Request[K]) (lo, hi K, ok bool) {
+	rr, ok := r.(interfaces.RangeRequest[K])
+	if !ok {
+		return lo, hi, false
+	}
+	lo, hi = rr.GetRange()
+	return lo, hi, true
+}
+
+// isRange reports whether begin message m reserves a key range.
+func isRange[K comparable](m message[K]) bool {
+	_, _, ok := m.keyset().span()
+	return ok
+}
+
+// interval is a key range [lo, hi) reserved by a message.
+type interval[K comparable] struct {
+	lo, hi K
+	m      message[K]
+}
+
+// intervals is an interval index of the key ranges reserved by messages, ordered by the low key
+// of each range, so only ranges starting below the queried range are examined.
+type intervals[K comparable] struct {
+	less    func(a, b K) bool
+	entries []interval[K]
+}
+
+// insert indexes the range [lo, hi) reserved by message m.
+func (iv *intervals[K]) insert(lo, hi K, m message[K]) {
+	i := sort.Search(len(iv.entries), func(i int) bool {
+		return iv.less(lo, iv.entries[i].lo)
+	})
+	iv.entries = append(iv.entries, interval[K]{})
+	copy(iv.entries[i+1:], iv.entries[i:])
+	iv.entries[i] = interval[K]{lo: lo, hi: hi, m: m}
+}
+
+// remove removes the range reserved by the exact message, returning false if not found.
+func (iv *intervals[K]) remove(m message[K]) bool {
+	for i, e := range iv.entries {
+		if e.m.same(m) {
+			iv.entries = append(iv.entries[:i:i], iv.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// find returns the indexed message which is the exact message m.
+func (iv *intervals[K]) find(m message[K]) (message[K], bool) {
+	for _, e := range iv.entries {
+		if e.m.same(m) {
+			return e.m, true
+		}
+	}
+	return nil, false
+}
+
+// overlapping returns the messages reserving a range overlapping [lo, hi), ordered by low key.
+func (iv *intervals[K]) overlapping(lo, hi K) []message[K] {
+	end := sort.Search(len(iv.entries), func(i int) bool {
+		return !iv.less(iv.entries[i].lo, hi)
+	})
+	var msgs []message[K]
+	for _, e := range iv.entries[:end] {
+		if iv.less(lo, e.hi) {
+			msgs = append(msgs, e.m)
+		}
+	}
+	return msgs
+}
+
+// containing returns the messages reserving a range containing key.
+func (iv *intervals[K]) containing(key K) []message[K] {
+	var msgs []message[K]
+	for _, e := range iv.entries {
+		if iv.less(key, e.lo) {
+			break
+		}
+		if iv.less(key, e.hi) {
+			msgs = append(msgs, e.m)
+		}
+	}
+	return msgs
+}
+
+// messages returns every indexed message, ordered by low key.
+func (iv *intervals[K]) messages() []message[K] {
+	msgs := make([]message[K], 0, len(iv.entries))
+	for _, e := range iv.entries {
+		msgs = append(msgs, e.m)
+	}
+	return msgs
+}
+
+// length returns the number of indexed ranges.
+func (iv *intervals[K]) length() int {
+	return len(iv.entries)
+}
+
+// keyIndex orders the keys of a map by key order, so the keys within a range are found without
+// scanning the map.  The keys are indexed on the first query, and maintained as keys are added and
+// removed thereafter, so supervisors never arbitrating a key range do not order their keys.
+type keyIndex[K comparable] struct {
+	less    func(a, b K) bool
+	keys    []K
+	ordered bool
+}
+
+// in returns the keys within [lo, hi) in key order, indexing the keys of m on the first call.  The
+// returned keys must not be retained once m is modified.
+func (ki *keyIndex[K]) in(m map[K][]message[K], lo, hi K) []K {
+	less := ki.less
+	if !ki.ordered {
+		ki.keys = make([]K, 0, len(m))
+		for k := range m {
+			ki.keys = append(ki.keys, k)
+		}
+		sort.Slice(ki.keys, func(i, j int) bool { return less(ki.keys[i], ki.keys[j]) })
+		ki.ordered = true
+	}
+	start := sort.Search(len(ki.keys), func(i int) bool { return !less(ki.keys[i], lo) })
+	end := sort.Search(len(ki.keys), func(i int) bool { return !less(ki.keys[i], hi) })
+	if start >= end {
+		return nil
+	}
+	return ki.keys[start:end]
+}
+
+// add indexes newly stored key, once indexed.
+func (ki *keyIndex[K]) add(key K) {
+	if !ki.ordered {
+		return
+	}
+	i := sort.Search(len(ki.keys), func(i int) bool { return !ki.less(ki.keys[i], key) })
+	ki.keys = append(ki.keys, key)
+	copy(ki.keys[i+1:], ki.keys[i:])
+	ki.keys[i] = key
+}
+
+// remove removes key no longer stored from the index, once indexed.
+func (ki *keyIndex[K]) remove(key K) {
+	if !ki.ordered {
+		return
+	}
+	i := sort.Search(len(ki.keys), func(i int) bool { return !ki.less(ki.keys[i], key) })
+	if i < len(ki.keys) && ki.keys[i] == key {
+		ki.keys = append(ki.keys[:i], ki.keys[i+1:]...)
+	}
+}
+
+// reset removes every key from the index, once every key is removed from the map.
+func (ki *keyIndex[K]) reset() {
+	ki.keys = ki.keys[:0]
+}
+
+// inRange reports whether key lies within [lo, hi).
+func (s *Supervisor[K]) inRange(key, lo, hi K) bool {
+	return !s.less(key, lo) && s.less(key, hi)
+}
+
+// rangeCompatible reports whether messages a and b may hold keys within a reserved range
+// concurrently: both are shared, or with conflict scheduling neither conflicts with the other.
+func (s *Supervisor[K]) rangeCompatible(a, b message[K]) bool {
+	if shared(a) && shared(b) {
+		return true
+	}
+	return s.conflictScheduling && !s.callConflicts(a.request(), b.request())
+}
+
+// contendsRange reports whether range messages m and p, reserving identical ranges, contend as
+// exclusive messages, so supersession applies between them.
+func (s *Supervisor[K]) contendsRange(m, p message[K]) bool {
+	mlo, mhi, _ := m.keyset().span()
+	plo, phi, ok := p.keyset().span()
+	return ok && mlo == plo && mhi == phi && !shared(m) && !shared(p) && !s.rangeCompatible(m, p)
+}
+
+// enqueRange arbitrates begin message m for a request reserving the key range [lo, hi).  The
+// request must supersede the in-flight request reserving the identical range, and displaces a
+// waiting request for the identical range it supersedes.  It is granted once no key in the range
+// is held by an incompatible request, and otherwise waits in the conflict index, reserving the
+// range against requests arriving later.
+func (s *Supervisor[K]) enqueRange(m message[K], lo, hi K) {
+	if s.less == nil {
+		s.cease(m, wrapOutcome(ErrInvalid, fmt.Errorf("%w: key type has no order", ErrKeyRange)))
+		return
+	}
+	if !s.less(lo, hi) {
+		s.cease(m, wrapOutcome(ErrInvalid, fmt.Errorf("%w: empty range [%v, %v)", ErrKeyRange, lo, hi)))
+		return
+	}
+
+	var inProcess []message[K]
+	for _, p := range s.processing.ranges.overlapping(lo, hi) {
+		if !s.contendsRange(m, p) {
+			continue
+		}
+		if err := s.callSupersedes(m.request(), p.request()); err != nil {
+			if !s.follow(m, p, err) {
+				s.cease(m, wrapOutcome(ErrSuperseded, err))
+			}
+			return
+		}
+		inProcess = append(inProcess, p)
+	}
+	var displaced []message[K]
+	for _, p := range s.pending.ranges.overlapping(lo, hi) {
+		if !s.contendsRange(m, p) {
+			continue
+		}
+		if err := s.callSupersedes(m.request(), p.request()); err != nil {
+			if !s.follow(m, p, err) {
+				s.cease(m, wrapOutcome(ErrSuperseded, err))
+			}
+			return
+		}
+		displaced = append(displaced, p)
+	}
+	for _, p := range displaced {
+		s.pending.remove(p)
+		s.metrics.DecWaitingMapDepth()
+		err := s.callSupersedes(p.request(), m.request())
+		if !s.follow(p, m, err) {
+			s.cease(p, wrapOutcome(ErrDisplaced, err))
+		}
+	}
+	if len(displaced) > 0 {
+		s.promoteRange(lo, hi)
+	}
+
+	if len(inProcess) == 0 && s.rangeGrantable(m, lo, hi) {
+		s.activateMessage(m)
+		return
+	}
+	s.pending.add(m)
+	s.waitlist(m)
+	for _, p := range inProcess {
+		s.preempt(p, m)
+	}
+}
+
+// rangeGrantable reports whether the range [lo, hi) of message m can be granted: no overlapping
+// range or key within the range is held by an incompatible message, and no incompatible message
+// which arrived earlier is waiting for an overlapping range or a key within the range.
+func (s *Supervisor[K]) rangeGrantable(m message[K], lo, hi K) bool {
+	for _, p := range s.processing.ranges.overlapping(lo, hi) {
+		if !s.rangeCompatible(m, p) {
+			return false
+		}
+	}
+	for _, k := range s.processing.keysIn(lo, hi) {
+		for _, h := range s.processing.holders(k) {
+			if !s.rangeCompatible(m, h) {
+				return false
+			}
+		}
+	}
+	for _, p := range s.pending.ranges.overlapping(lo, hi) {
+		if arrival(p) < arrival(m) && !s.rangeCompatible(m, p) {
+			return false
+		}
+	}
+	for _, k := range s.pending.keysIn(lo, hi) {
+		for _, p := range s.pending.bucket(k) {
+			if arrival(p) < arrival(m) && !s.rangeCompatible(m, p) {
+				return false
+			}
+		}
+	}
+	for _, k := range s.waiting.keysIn(lo, hi) {
+		for _, w := range s.waiting.queues[k] {
+			if arrival(w) < arrival(m) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rangeBlocks reports whether any of keys of point message m is within a range held by an
+// incompatible message, or reserved by an incompatible message which arrived before m.
+func (s *Supervisor[K]) rangeBlocks(m message[K], keys []K) bool {
+	if s.less == nil {
+		return false
+	}
+	for _, k := range keys {
+		for _, p := range s.processing.ranges.containing(k) {
+			if !s.rangeCompatible(m, p) {
+				return true
+			}
+		}
+		for _, p := range s.pending.ranges.containing(k) {
+			if arrival(p) < arrival(m) && !s.rangeCompatible(m, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// promoteRange promotes waiting messages once the range [lo, hi) has been released: first pending
+// messages for keys within the range, or overlapping ranges, then the next waiting message for
+// each key within the range.
+func (s *Supervisor[K]) promoteRange(lo, hi K) {
+	keys := append([]K(nil), s.pending.keysIn(lo, hi)...)
+	for _, k := range s.waiting.keysIn(lo, hi) {
+		if _, pending := s.pending.buckets[k]; !pending {
+			keys = append(keys, k)
+		}
+	}
+	candidates := append(s.pending.candidates(keys), s.pending.ranges.overlapping(lo, hi)...)
+	sortByArrival(candidates)
+	s.promote(candidates)
+	s.promoteKeys(keys)
+}
+
+// releaseMessage promotes waiting messages once the keys, or key range, held by message m have
+// been released.
+func (s *Supervisor[K]) releaseMessage(m message[K]) {
+	s.freeKeys(m)
+	if lo, hi, ok := m.keyset().span(); ok {
+		s.promoteRange(lo, hi)
+		return
+	}
+	s.release(m.keyset().keys)
+}
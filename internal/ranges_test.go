@@ -0,0 +1,225 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// rangeReq is a testReq reserving the key range [key, hi).
+type rangeReq struct {
+	testReq
+	hi int64
+}
+
+func (p *rangeReq) GetRange() (int64, int64) {
+	return p.key, p.hi
+}
+
+func Test_naturalOrder(t *testing.T) {
+	type tenantID int32
+	if less := naturalOrder[int64](); less == nil || !less(-2, 1) || less(1, 1) {
+		t.Error("expected int64 keys ordered naturally")
+	}
+	if less := naturalOrder[string](); less == nil || !less("a", "b") || less("b", "a") {
+		t.Error("expected string keys ordered naturally")
+	}
+	if less := naturalOrder[tenantID](); less == nil || !less(1, 2) {
+		t.Error("expected named integer keys ordered naturally")
+	}
+	if less := naturalOrder[[2]int64](); less != nil {
+		t.Error("expected array keys to have no natural order")
+	}
+}
+
+func Test_intervals(t *testing.T) {
+	newMsg := func(lo, hi int64) message[int64] {
+		return &beginMessage[int64]{req: &rangeReq{testReq: testReq{key: lo}, hi: hi}}
+	}
+	a, b, c := newMsg(10, 20), newMsg(0, 5), newMsg(15, 30)
+	iv := intervals[int64]{less: naturalOrder[int64]()}
+	for _, m := range []message[int64]{a, b, c} {
+		lo, hi, _ := rangeOf(m.request())
+		iv.insert(lo, hi, m)
+	}
+
+	tests := map[string]struct {
+		got  []message[int64]
+		want []message[int64]
+	}{
+		"overlapping both":     {got: iv.overlapping(18, 19), want: []message[int64]{a, c}},
+		"overlapping adjacent": {got: iv.overlapping(5, 10), want: nil},
+		"overlapping spanning": {got: iv.overlapping(0, 100), want: []message[int64]{b, a, c}},
+		"containing low key":   {got: iv.containing(10), want: []message[int64]{a}},
+		"containing high key":  {got: iv.containing(20), want: []message[int64]{c}},
+		"containing none":      {got: iv.containing(7), want: nil},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if len(tc.got) != len(tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, tc.got)
+			}
+			for i := range tc.want {
+				if tc.got[i] != tc.want[i] {
+					t.Errorf("expected %v, got %v", tc.want, tc.got)
+					break
+				}
+			}
+		})
+	}
+
+	if !iv.remove(a) || iv.remove(a) || iv.length() != 2 {
+		t.Error("expected range removed once")
+	}
+	if got := iv.overlapping(18, 19); len(got) != 1 || got[0] != c {
+		t.Errorf("expected removed range no longer overlapping, got %v", got)
+	}
+}
+
+func Test_keyIndex(t *testing.T) {
+	m := map[int64][]message[int64]{5: nil, 1: nil, 9: nil}
+	ki := keyIndex[int64]{less: naturalOrder[int64]()}
+	ki.add(3) // not yet indexed
+	if got := ki.in(m, 0, 9); len(got) != 2 || got[0] != 1 || got[1] != 5 {
+		t.Errorf("expected keys [1 5] indexed in key order, got %v", got)
+	}
+	ki.add(7)
+	ki.remove(5)
+	if got := ki.in(m, 2, 10); len(got) != 2 || got[0] != 7 || got[1] != 9 {
+		t.Errorf("expected keys [7 9] once added and removed, got %v", got)
+	}
+	ki.reset()
+	if got := ki.in(m, 0, 10); len(got) != 0 {
+		t.Errorf("expected no keys once reset, got %v", got)
+	}
+}
+
+func Test_SupervisorRanges(t *testing.T) {
+	type req struct {
+		name  string
+		key   int64
+		hi    int64 // if set, the request reserves [key, hi)
+		value int64
+	}
+	tests := map[string]tableTest[req]{
+		"point within held range waits": {
+			hold:    []req{{name: "range", key: 10, hi: 20, value: 9}},
+			reqs:    []req{{name: "point", key: 15, value: 10}},
+			waiting: 1,
+			wantRun: []string{"point"},
+		},
+		"point outside held range proceeds": {
+			hold:    []req{{name: "range", key: 10, hi: 20, value: 9}},
+			reqs:    []req{{name: "point", key: 20, value: 10}},
+			early:   []string{"point"},
+			wantRun: []string{"point"},
+		},
+		"range waits for held point": {
+			hold:    []req{{name: "point", key: 12, value: 20}},
+			reqs:    []req{{name: "range", key: 10, hi: 20, value: 10}},
+			waiting: 1,
+			wantRun: []string{"range"},
+		},
+		"overlapping ranges wait": {
+			hold:    []req{{name: "held", key: 10, hi: 20, value: 20}},
+			reqs:    []req{{name: "range", key: 15, hi: 25, value: 10}},
+			waiting: 1,
+			wantRun: []string{"range"},
+		},
+		"disjoint ranges proceed": {
+			hold:    []req{{name: "held", key: 10, hi: 20, value: 9}},
+			reqs:    []req{{name: "range", key: 20, hi: 30, value: 10}},
+			early:   []string{"range"},
+			wantRun: []string{"range"},
+		},
+		"identical range superseded": {
+			hold:     []req{{name: "held", key: 10, hi: 20, value: 20}},
+			reqs:     []req{{name: "range", key: 10, hi: 20, value: 10}},
+			wantErrs: map[string]error{"range": ErrSuperseded},
+		},
+		"identical waiting range displaced": {
+			hold: []req{{name: "held", key: 10, hi: 20, value: 9}},
+			reqs: []req{
+				{name: "range10", key: 10, hi: 20, value: 10},
+				{name: "range11", key: 10, hi: 20, value: 11},
+			},
+			waiting:  1,
+			wantRun:  []string{"range11"},
+			wantErrs: map[string]error{"range10": ErrDisplaced},
+		},
+		"waiting range reserves points": {
+			hold: []req{{name: "held", key: 12, value: 9}},
+			reqs: []req{
+				{name: "range", key: 10, hi: 20, value: 10},
+				{name: "point", key: 15, value: 10},
+			},
+			waiting: 2,
+			wantRun: []string{"range", "point"},
+		},
+		"empty range invalid": {
+			reqs:     []req{{name: "range", key: 20, hi: 10, value: 10}},
+			wantErrs: map[string]error{"range": ErrKeyRange},
+		},
+	}
+
+	newReq := func(r req) (string, interfaces.Request[int64]) {
+		if r.hi == 0 {
+			return r.name, &testReq{key: r.key, value: r.value}
+		}
+		return r.name, &rangeReq{testReq: testReq{key: r.key, value: r.value}, hi: r.hi}
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			runTableTest(t, tc, newReq)
+		})
+	}
+}
+
+// Benchmark_rangeScheduling arbitrates a key range while many keys outside of it are held, each with
+// a request waiting, so the cost of finding the keys within the range is measured.
+func Benchmark_rangeScheduling(b *testing.B) {
+	const held = 1000
+	b.ReportAllocs()
+	arbiter, db, ctx, _, li, err := testSetup()
+	if err != nil {
+		b.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	leases := make([]*Lease[int64], 0, held)
+	tickets := make([]*Ticket, 0, held)
+	for i := int64(1); i <= held; i++ {
+		r := &testReq{key: i, value: 1}
+		setupTestItem(r, db)
+		lease, err := arbiter.Acquire(ctx, r)
+		if err != nil {
+			b.Fatalf("expected Acquire of key %d to succeed, got: %v", i, err)
+		}
+		leases = append(leases, lease)
+		w := &testReq{key: i, value: 2}
+		setupTestItem(w, db)
+		tickets = append(tickets, arbiter.Submit(ctx, w, func(context.Context) error { return nil }))
+	}
+	waitForGauge(b, li, at.WaitingMapDepth, held)
+
+	noop := func(context.Context) error { return nil }
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		r := &rangeReq{testReq: testReq{key: -10, value: int64(n + 1)}, hi: 0}
+		setupTestItem(&r.testReq, db)
+		if err := arbiter.WithWorker(ctx, r, noop); err != nil {
+			b.Fatalf("expected range request to succeed, got: %v", err)
+		}
+	}
+	b.StopTimer()
+
+	for _, lease := range leases {
+		lease.Abort()
+	}
+	for _, ticket := range tickets {
+		<-ticket.Done()
+	}
+}
@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// RateLimitPolicy determines how requests activated without rate limit tokens available are
+// handled.
+type RateLimitPolicy int
+
+// Set of RateLimitPolicy values.  RateLimitDelay holds the request (and its key) in the admission
+// queue until tokens are available.  RateLimitReject ceases the request with ErrRateLimited, and
+// the next waiting request for the key is promoted.  Requests already awaiting admission for a
+// processing slot wait for tokens under either policy.
+const (
+	RateLimitDelay  RateLimitPolicy = iota // RateLimitDelay is the default rate limit behavior.
+	RateLimitReject                        // RateLimitReject ceases requests without tokens available.
+)
+
+func (p RateLimitPolicy) String() string {
+	return [...]string{"delay", "reject"}[p]
+}
+
+// valid confirms the policy is one of the defined RateLimitPolicy values.
+func (p RateLimitPolicy) valid() error {
+	if p < RateLimitDelay || p > RateLimitReject {
+		return fmt.Errorf("unknown rate limit policy %d", p)
+	}
+	return nil
+}
+
+// SetRateLimit sets a token bucket limiting the rate at which requests proceed across all keys,
+// to rate tokens per second with bursts of up to burst tokens.  Requests cost one token, or the
+// result of Cost if they implement interfaces.Weighted.  Zero rate (the default) disables the limit.
+func SetRateLimit(rate, burst float64) SupervisorOption {
+	return func(c *config) error {
+		if err := validRate(rate, burst); err != nil {
+			return err
+		}
+		c.rate, c.burst = rate, burst
+		return nil
+	}
+}
+
+// SetKeyRateLimit sets a token bucket per key, limiting the rate at which requests for each key
+// proceed to rate tokens per second with bursts of up to burst tokens.  Zero rate (the default)
+// disables per key limits.
+func SetKeyRateLimit(rate, burst float64) SupervisorOption {
+	return func(c *config) error {
+		if err := validRate(rate, burst); err != nil {
+			return err
+		}
+		c.keyRate, c.keyBurst = rate, burst
+		return nil
+	}
+}
+
+// SetRateLimitPolicy sets the policy for requests activated without rate limit tokens available.
+func SetRateLimitPolicy(p RateLimitPolicy) SupervisorOption {
+	return func(c *config) error {
+		if err := p.valid(); err != nil {
+			return err
+		}
+		c.ratePolicy = p
+		return nil
+	}
+}
+
+// validRate confirms a rate limit is either disabled, or has a positive burst.
+func validRate(rate, burst float64) error {
+	if rate < 0 {
+		return fmt.Errorf("rate limit must not be negative")
+	}
+	if rate > 0 && burst <= 0 {
+		return fmt.Errorf("rate limit burst must be positive")
+	}
+	return nil
+}
+
+// tokenBucket accrues tokens at rate per second, up to burst tokens.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate, burst float64, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// refill adds the tokens accrued since the last refill, up to burst.
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+}
+
+// wait returns the time until cost tokens are available, or zero if available now.  Costs above
+// burst are capped at burst, so wait for a full bucket.
+func (b *tokenBucket) wait(now time.Time, cost float64) time.Duration {
+	b.refill(now)
+	cost = math.Min(cost, b.burst)
+	if b.tokens >= cost {
+		return 0
+	}
+	return time.Duration(math.Ceil((cost - b.tokens) / b.rate * float64(time.Second)))
+}
+
+// take removes cost tokens (capped at burst) from the bucket.
+func (b *tokenBucket) take(now time.Time, cost float64) {
+	b.refill(now)
+	b.tokens -= math.Min(cost, b.burst)
+}
+
+// full reports whether the bucket has refilled completely, so is equivalent to a new bucket.
+func (b *tokenBucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
+
+// minPrune is the minimum number of per key buckets before full buckets are pruned.
+const minPrune = 64
+
+// rateLimiter holds the global and per key token buckets of the supervisor, and the timer used
+// to wake the supervisor once tokens are available for delayed requests.
+type rateLimiter[K comparable] struct {
+	global   *tokenBucket
+	keyRate  float64
+	keyBurst float64
+	keys     map[K]*tokenBucket
+	pruneAt  int
+	policy   RateLimitPolicy
+	// globalBlocked is set while a message awaiting admission waits on the global bucket, so
+	// later messages queue behind it.
+	globalBlocked bool
+	timer         *time.Timer
+	wake          <-chan time.Time
+	wakeAt        time.Time
+}
+
+// newRateLimiter returns the rate limiter for the configuration, or nil if rate limiting is
+// disabled.
+func newRateLimiter[K comparable](c *config, now time.Time) *rateLimiter[K] {
+	if c.rate <= 0 && c.keyRate <= 0 {
+		return nil
+	}
+	l := &rateLimiter[K]{
+		keyRate:  c.keyRate,
+		keyBurst: c.keyBurst,
+		keys:     make(map[K]*tokenBucket),
+		pruneAt:  minPrune,
+		policy:   c.ratePolicy,
+	}
+	if c.rate > 0 {
+		l.global = newTokenBucket(c.rate, c.burst, now)
+	}
+	return l
+}
+
+// wait returns the time until cost tokens are available for key from every bucket, and the
+// scope of the limit: "global" if the global bucket lacks tokens, otherwise "key".
+func (l *rateLimiter[K]) wait(key K, cost float64, now time.Time) (time.Duration, string) {
+	var d time.Duration
+	if b, ok := l.keys[key]; ok {
+		d = b.wait(now, cost)
+	}
+	if l.global != nil {
+		if gd := l.global.wait(now, cost); gd > 0 {
+			if gd > d {
+				d = gd
+			}
+			return d, "global"
+		}
+	}
+	return d, "key"
+}
+
+// take removes cost tokens for key from every bucket.
+func (l *rateLimiter[K]) take(key K, cost float64, now time.Time) {
+	if l.global != nil {
+		l.global.take(now, cost)
+	}
+	if l.keyRate <= 0 {
+		return
+	}
+	b, ok := l.keys[key]
+	if !ok {
+		l.prune(now)
+		b = newTokenBucket(l.keyRate, l.keyBurst, now)
+		l.keys[key] = b
+	}
+	b.take(now, cost)
+}
+
+// prune removes per key buckets which have refilled completely, once the number of buckets
+// reaches pruneAt, so buckets are only retained for recently active keys.
+func (l *rateLimiter[K]) prune(now time.Time) {
+	if len(l.keys) < l.pruneAt {
+		return
+	}
+	for key, b := range l.keys {
+		if b.full(now) {
+			delete(l.keys, key)
+		}
+	}
+	l.pruneAt = 2 * len(l.keys)
+	if l.pruneAt < minPrune {
+		l.pruneAt = minPrune
+	}
+}
+
+// schedule arms the wake timer to fire after d, unless already armed to fire sooner.
+func (l *rateLimiter[K]) schedule(now time.Time, d time.Duration) {
+	at := now.Add(d)
+	if l.wake != nil && !l.wakeAt.After(at) {
+		return
+	}
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.timer = time.NewTimer(d)
+	l.wake = l.timer.C
+	l.wakeAt = at
+}
+
+// wakeup returns the channel signaling tokens may be available for delayed messages, or nil if
+// no wake is scheduled.
+func (l *rateLimiter[K]) wakeup() <-chan time.Time {
+	if l == nil {
+		return nil
+	}
+	return l.wake
+}
+
+// rateWait returns the time until rate limit tokens are available for message m, and the scope
+// of the limit, or zero if rate limiting is disabled.
+func (s *Supervisor[K]) rateWait(m message[K], now time.Time) (time.Duration, string) {
+	if s.limiter == nil {
+		return 0, ""
+	}
+	d, scope := s.limiter.wait(m.keyset().key, s.callCost(m.request()), now)
+	s.reportTokens()
+	return d, scope
+}
+
+// rateLimited counts message m as rate limited, and either ceases it with ErrRateLimited,
+// returning false, or queues it for admission until tokens are available after d.
+func (s *Supervisor[K]) rateLimited(m message[K], d time.Duration, scope string, now time.Time) bool {
+	s.metrics.RateLimited(telemetry.Labels{
+		"policy": s.limiter.policy.String(),
+		"scope":  scope,
+	})
+	if s.limiter.policy == RateLimitReject {
+		s.cease(m, ErrRateLimited)
+		return false
+	}
+	if scope == "global" {
+		s.limiter.globalBlocked = true
+	}
+	s.queueAdmission(m)
+	s.limiter.schedule(now, d)
+	return true
+}
+
+// takeTokens removes the rate limit tokens for message m as it proceeds.
+func (s *Supervisor[K]) takeTokens(m message[K]) {
+	if s.limiter == nil {
+		return
+	}
+	s.limiter.take(m.keyset().key, s.callCost(m.request()), time.Now())
+	s.reportTokens()
+}
+
+// reportTokens updates the global rate limit tokens gauge.
+func (s *Supervisor[K]) reportTokens() {
+	if s.limiter.global != nil {
+		s.metrics.RateLimitTokens(int64(s.limiter.global.tokens))
+	}
+}
+
+// nextAdmissible returns the index of the first admission queue entry with rate limit tokens
+// available, or -1 and the shortest wait for tokens if none are.  Entries after one waiting on
+// the global bucket are not admissible, so requests are not starved by cheaper requests.
+func (s *Supervisor[K]) nextAdmissible(now time.Time) (int, time.Duration) {
+	if s.limiter == nil {
+		if len(s.admission) == 0 {
+			return -1, 0
+		}
+		return 0, 0
+	}
+	s.limiter.globalBlocked = false
+	var wait time.Duration
+	for i, entry := range s.admission {
+		d, scope := s.rateWait(entry.m, now)
+		if d == 0 {
+			return i, 0
+		}
+		if wait == 0 || d < wait {
+			wait = d
+		}
+		if scope == "global" {
+			s.limiter.globalBlocked = true
+			break
+		}
+	}
+	return -1, wait
+}
+
+// admissionBlocked reports whether a newly activated message must queue behind messages already
+// awaiting admission.  Messages delayed only by per key rate limits do not block other keys.
+func (s *Supervisor[K]) admissionBlocked() bool {
+	return len(s.admission) > 0 && (s.limiter == nil || s.limiter.globalBlocked)
+}
@@ -0,0 +1,149 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// weightedReq is a testReq consuming cost rate limit tokens.
+type weightedReq struct {
+	testReq
+	cost float64
+}
+
+func (w *weightedReq) Cost() float64 {
+	return w.cost
+}
+
+func Test_tokenBucket(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(10, 2, start)
+
+	steps := []struct {
+		at   time.Duration // offset from start
+		cost float64
+		take bool
+		want time.Duration
+	}{
+		{at: 0, cost: 2, take: true, want: 0},
+		{at: 0, cost: 1, want: 100 * time.Millisecond},
+		{at: 50 * time.Millisecond, cost: 1, want: 50 * time.Millisecond},
+		{at: 100 * time.Millisecond, cost: 1, take: true, want: 0},
+		// Costs above burst wait for a full bucket.
+		{at: 100 * time.Millisecond, cost: 5, want: 200 * time.Millisecond},
+		{at: 10 * time.Second, cost: 5, want: 0},
+	}
+	for i, step := range steps {
+		now := start.Add(step.at)
+		if got := b.wait(now, step.cost); got != step.want {
+			t.Errorf("step %d: expected wait %v, got %v", i, step.want, got)
+		}
+		if step.take {
+			b.take(now, step.cost)
+		}
+	}
+}
+
+func Test_SupervisorRateLimit(t *testing.T) {
+	tests := map[string]struct {
+		opts        []SupervisorOption
+		reqs        []string
+		costs       []float64 // cost of each request, where non-zero
+		wantErrs    []error
+		minDelay    []time.Duration
+		maxDelay    []time.Duration // zero if unchecked
+		wantLimited int64
+	}{
+		"global limit delays other keys": {
+			opts:        []SupervisorOption{SetRateLimit(20, 1)},
+			reqs:        []string{"record1version9", "record2version10"},
+			minDelay:    []time.Duration{0, 40 * time.Millisecond},
+			wantLimited: 1,
+		},
+		"key limit delays only its key": {
+			opts:        []SupervisorOption{SetKeyRateLimit(20, 1)},
+			reqs:        []string{"record1version9", "record1version10", "record2version10"},
+			minDelay:    []time.Duration{0, 40 * time.Millisecond, 0},
+			maxDelay:    []time.Duration{0, 0, 30 * time.Millisecond},
+			wantLimited: 1,
+		},
+		"reject without tokens": {
+			opts:        []SupervisorOption{SetKeyRateLimit(1, 1), SetRateLimitPolicy(RateLimitReject)},
+			reqs:        []string{"record1version9", "record1version10"},
+			wantErrs:    []error{nil, ErrRateLimited},
+			wantLimited: 1,
+		},
+		"weighted requests": {
+			opts:        []SupervisorOption{SetRateLimit(100, 10)},
+			reqs:        []string{"record1version9", "record2version10"},
+			costs:       []float64{10, 10},
+			minDelay:    []time.Duration{0, 80 * time.Millisecond},
+			wantLimited: 1,
+		},
+		"cheaper requests queue behind global limit": {
+			opts:        []SupervisorOption{SetRateLimit(100, 10)},
+			reqs:        []string{"record1version9", "record2version10", "record3version30"},
+			costs:       []float64{10, 10, 1},
+			minDelay:    []time.Duration{0, 80 * time.Millisecond, 80 * time.Millisecond},
+			wantLimited: 2,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup(tc.opts...)
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			start := time.Now()
+			elapsed := make([]time.Duration, len(tc.reqs))
+			errs := make([]error, len(tc.reqs))
+			var wg sync.WaitGroup
+			for i, name := range tc.reqs {
+				tr := requestDefs[name]
+				setupTestItem(&tr, db)
+				var r interfaces.Request[int64] = &tr
+				if tc.costs != nil && tc.costs[i] > 0 {
+					r = &weightedReq{testReq: tr, cost: tc.costs[i]}
+				}
+				ticket := arbiter.Submit(ctx, r, func(context.Context) error { return nil })
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = ticket.Wait(ctx)
+					elapsed[i] = time.Since(start)
+				}(i)
+			}
+			wg.Wait()
+
+			for i := range tc.reqs {
+				var want error
+				if tc.wantErrs != nil {
+					want = tc.wantErrs[i]
+				}
+				if !errors.Is(errs[i], want) || (want == nil && errs[i] != nil) {
+					t.Errorf("%s: expected error %v, got: %v", tc.reqs[i], want, errs[i])
+				}
+				if tc.minDelay != nil && elapsed[i] < tc.minDelay[i] {
+					t.Errorf("%s: expected delay of at least %v, got %v", tc.reqs[i], tc.minDelay[i], elapsed[i])
+				}
+				if tc.maxDelay != nil && tc.maxDelay[i] > 0 && elapsed[i] > tc.maxDelay[i] {
+					t.Errorf("%s: expected delay of at most %v, got %v", tc.reqs[i], tc.maxDelay[i], elapsed[i])
+				}
+			}
+			waitForGauge(t, li, at.AdmissionQueueDepth, 0)
+			if got := li.SnapMetrics().Counters[at.RateLimited]; got != tc.wantLimited {
+				t.Errorf("expected %d requests rate limited, got %d", tc.wantLimited, got)
+			}
+		})
+	}
+}
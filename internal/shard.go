@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	"github.com/btsomogyi/arbiter/logging"
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// ShardedSupervisor distributes requests across a number of independent Supervisors (shards),
+// each with its own queue, processing loop, and processing and waiting maps.  Requests are
+// assigned to shards by a hash of their key, so all requests for a given key are arbitrated by
+// the same shard, preserving ordering and supersession per key.
+type ShardedSupervisor[K comparable] struct {
+	shards []*Supervisor[K]
+	hash   func(K) uint64
+}
+
+// SetShardCount sets the number of shards used by a ShardedSupervisor.  If not provided, the
+// number of shards defaults to GOMAXPROCS.
+func SetShardCount(n uint) SupervisorOption {
+	return func(c *config) error {
+		if n == 0 {
+			return fmt.Errorf("shard count must be at least 1")
+		}
+		c.shardCount = n
+		return nil
+	}
+}
+
+// SetShardHash sets the function used by a ShardedSupervisor to hash request keys to shards.
+// The key type K must match that of the ShardedSupervisor.  If not provided, a default hash
+// is used which supports all key types.
+func SetShardHash[K comparable](h func(K) uint64) SupervisorOption {
+	return func(c *config) error {
+		if h == nil {
+			return fmt.Errorf("shard hash function must not be nil")
+		}
+		c.shardHash = h
+		return nil
+	}
+}
+
+// NewShardedSupervisor returns an initialized ShardedSupervisor.  All options apply to each
+// shard, with the exception of the Instrumentor, which receives metrics from every shard
+// labeled with the shard index.
+func NewShardedSupervisor[K comparable](opts ...SupervisorOption) (*ShardedSupervisor[K], error) {
+	cfg, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &ShardedSupervisor[K]{
+		hash: defaultKeyHash[K],
+	}
+	if _, err := keyOrder[K](cfg); err != nil {
+		return nil, err
+	}
+	if _, err := watchdogStuck[K](cfg); err != nil {
+		return nil, err
+	}
+	if cfg.shardHash != nil {
+		h, ok := cfg.shardHash.(func(K) uint64)
+		if !ok {
+			return nil, fmt.Errorf("shard hash function %T does not match key type", cfg.shardHash)
+		}
+		ss.hash = h
+	}
+
+	count := cfg.shardCount
+	if count == 0 {
+		count = uint(runtime.GOMAXPROCS(0))
+	}
+	instrument := cfg.Instrument
+	if instrument == nil {
+		instrument = telemetry.NewNopInstrumentor()
+	}
+	for i := uint(0); i < count; i++ {
+		shardCfg := *cfg
+		shardCfg.Instrument = telemetry.NewLabeledInstrumentor(instrument, telemetry.Labels{
+			"shard": strconv.FormatUint(uint64(i), 10),
+		})
+		if cfg.logger != nil {
+			shardCfg.logger = cfg.logger.WithFields(logging.LogTuple{Field: "shard", Value: i})
+		}
+		s := &Supervisor[K]{}
+		s.init(&shardCfg)
+		ss.shards = append(ss.shards, s)
+	}
+	return ss, nil
+}
+
+// Process starts the processing loop of every shard, returning once all have returned.
+func (ss *ShardedSupervisor[K]) Process() {
+	var wg sync.WaitGroup
+	for _, s := range ss.shards {
+		wg.Add(1)
+		go func(s *Supervisor[K]) {
+			defer wg.Done()
+			s.Process()
+		}(s)
+	}
+	wg.Wait()
+}
+
+// Terminate shutdowns every shard immediately (see Supervisor.Terminate).
+func (ss *ShardedSupervisor[K]) Terminate() {
+	for _, s := range ss.shards {
+		s.Terminate()
+	}
+}
+
+// Shutdown gracefully stops every shard concurrently (see Supervisor.Shutdown), returning
+// once all shards have drained, or with the context error if ctx expires first.
+func (ss *ShardedSupervisor[K]) Shutdown(ctx context.Context) error {
+	errs := make(chan error, len(ss.shards))
+	for _, s := range ss.shards {
+		go func(s *Supervisor[K]) {
+			errs <- s.Shutdown(ctx)
+		}(s)
+	}
+	var err error
+	for range ss.shards {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// WithWorker arbitrates the request on the shard assigned to its key (see Supervisor.WithWorker).
+func (ss *ShardedSupervisor[K]) WithWorker(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) error {
+	return ss.WithWorkerOutcome(ctx, r, fn).Err
+}
+
+// Submit arbitrates the request asynchronously on the shard assigned to its key (see Supervisor.Submit).
+func (ss *ShardedSupervisor[K]) Submit(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) *Ticket {
+	s, ks, keyErr := ss.route(r)
+	return s.submit(ctx, r, ks, keyErr, fn, false)
+}
+
+// TryWithWorker arbitrates the request on the shard assigned to its key, failing fast if the
+// shard queue is full (see Supervisor.TryWithWorker).
+func (ss *ShardedSupervisor[K]) TryWithWorker(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) error {
+	s, ks, keyErr := ss.route(r)
+	t := s.submit(ctx, r, ks, keyErr, fn, true)
+	<-t.done
+	return t.outcome.Err
+}
+
+// Acquire arbitrates the request on the shard assigned to its key (see Supervisor.Acquire).
+func (ss *ShardedSupervisor[K]) Acquire(ctx context.Context, r interfaces.Request[K]) (*Lease[K], error) {
+	s, ks, keyErr := ss.route(r)
+	return s.acquire(ctx, r, ks, keyErr)
+}
+
+// WithWorkerOutcome arbitrates the request on the shard assigned to its key (see
+// Supervisor.WithWorkerOutcome).
+func (ss *ShardedSupervisor[K]) WithWorkerOutcome(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) Outcome {
+	t := ss.Submit(ctx, r, fn)
+	<-t.done
+	return t.outcome
+}
+
+// route resolves the keys of request r, recovering any panic as the Supervisor does, and returns
+// the shard assigned to them with the keys, or the error ceasing the request (see
+// generateWorker).  Requests whose keys cannot be resolved are ceased by the first shard, and
+// requests whose keys are assigned to more than one shard with ErrCrossShard by the shard of
+// GetKey.  Keys depended on are held to the same shard, so each shard detects every dependency
+// cycle among its requests.  Range requests are always ceased with ErrCrossShard, as the keys of a
+// range are hashed across every shard.
+func (ss *ShardedSupervisor[K]) route(r interfaces.Request[K]) (*Supervisor[K], *keyset[K], error) {
+	ks, err := ss.shards[0].resolveKeys(r)
+	if err != nil {
+		return ss.shards[0], nil, err
+	}
+	s := ss.shard(ks.key)
+	if lo, hi, ok := ks.span(); ok {
+		return s, nil, fmt.Errorf("%w: range [%v, %v)", ErrCrossShard, lo, hi)
+	}
+	for _, key := range ks.keys {
+		if ss.shard(key) != s {
+			return s, nil, fmt.Errorf("%w: keys %v", ErrCrossShard, ks.keys)
+		}
+	}
+	for _, key := range ks.deps {
+		if ss.shard(key) != s {
+			return s, nil, fmt.Errorf("%w: key %v depends on %v", ErrCrossShard, ks.key, ks.deps)
+		}
+	}
+	return s, ks, nil
+}
+
+// shard returns the Supervisor responsible for key.
+func (ss *ShardedSupervisor[K]) shard(key K) *Supervisor[K] {
+	return ss.shards[ss.hash(key)%uint64(len(ss.shards))]
+}
+
+// defaultKeyHash hashes keys of any comparable type.  Integer and string keys are hashed
+// directly, while other types are hashed by their Go syntax representation.
+func defaultKeyHash[K comparable](key K) uint64 {
+	switch k := any(key).(type) {
+	case int:
+		return mix64(uint64(k))
+	case int32:
+		return mix64(uint64(k))
+	case int64:
+		return mix64(uint64(k))
+	case uint:
+		return mix64(uint64(k))
+	case uint32:
+		return mix64(uint64(k))
+	case uint64:
+		return mix64(k)
+	case string:
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		return h.Sum64()
+	case [16]byte:
+		return mix64(binary.LittleEndian.Uint64(k[:8]) ^ binary.LittleEndian.Uint64(k[8:]))
+	default:
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%#v", key)
+		return h.Sum64()
+	}
+}
+
+// mix64 is the splitmix64 finalizer, spreading sequential integer keys evenly across shards.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
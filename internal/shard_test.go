@@ -0,0 +1,219 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_ShardedSupervisor(t *testing.T) {
+	li := at.NewLocalInstrumentor()
+	ss, err := NewShardedSupervisor[int64](SetShardCount(4), SetInstrumentor(li))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go ss.Process()
+	defer ss.Terminate()
+
+	var db mtxMap
+	var wg sync.WaitGroup
+	// Submit increasing versions for a set of keys; each key is serialized by its shard, so
+	// the final version for every key must be the highest submitted.
+	const keys, versions = 32, 8
+	for v := int64(1); v <= versions; v++ {
+		for k := int64(1); k <= keys; k++ {
+			r := &testReq{key: k, value: v}
+			setupTestItem(r, &db)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Superseded and invalid requests are expected to return errors.
+				_ = ss.WithWorker(context.Background(), r, func(context.Context) error { return nil })
+			}()
+		}
+		wg.Wait()
+	}
+
+	for k := int64(1); k <= keys; k++ {
+		checkDb(t, &db, &testReq{key: k, value: versions})
+	}
+	checkMetrics(t, li.SnapMetrics(), at.MetricSnap{
+		Gauges: map[at.MetricGauge]int64{
+			at.QueueChanDepth:     0,
+			at.ProcessingMapDepth: 0,
+			at.WaitingMapDepth:    0,
+		},
+	})
+	if err := ss.Shutdown(context.Background()); err != nil {
+		t.Errorf("expected Shutdown to drain, got: %v", err)
+	}
+}
+
+func Test_ShardedSupervisorRouting(t *testing.T) {
+	entries := map[string]func(context.Context, *ShardedSupervisor[int64], interfaces.Request[int64]) error{
+		"WithWorker": func(ctx context.Context, ss *ShardedSupervisor[int64], r interfaces.Request[int64]) error {
+			return ss.WithWorker(ctx, r, func(context.Context) error { return nil })
+		},
+		"WithWorkerOutcome": func(ctx context.Context, ss *ShardedSupervisor[int64], r interfaces.Request[int64]) error {
+			return ss.WithWorkerOutcome(ctx, r, func(context.Context) error { return nil }).Err
+		},
+		"Submit": func(ctx context.Context, ss *ShardedSupervisor[int64], r interfaces.Request[int64]) error {
+			return ss.Submit(ctx, r, func(context.Context) error { return nil }).Wait(ctx)
+		},
+		"TryWithWorker": func(ctx context.Context, ss *ShardedSupervisor[int64], r interfaces.Request[int64]) error {
+			return ss.TryWithWorker(ctx, r, func(context.Context) error { return nil })
+		},
+		"Acquire": func(ctx context.Context, ss *ShardedSupervisor[int64], r interfaces.Request[int64]) error {
+			lease, err := ss.Acquire(ctx, r)
+			if err != nil {
+				return err
+			}
+			return lease.Commit()
+		},
+	}
+	// Keys are assigned to shard key % 4.
+	tests := map[string]struct {
+		r       testRequest
+		wantErr error
+		panics  bool // expect a PanicError rather than wantErr
+	}{
+		"routed": {
+			r: &testReq{key: 1, value: 10},
+		},
+		"multi-key on one shard": {
+			r: &multiReq{testReq: testReq{key: 1, value: 10}, keys: []int64{5, 9}},
+		},
+		"multi-key across shards": {
+			r:       &multiReq{testReq: testReq{key: 1, value: 10}, keys: []int64{5, 2}},
+			wantErr: ErrCrossShard,
+		},
+		"path on one shard": {
+			r: &pathReq{testReq: testReq{key: 9, value: 10}, path: []int64{1, 5, 9}},
+		},
+		"path across shards": {
+			r:       &pathReq{testReq: testReq{key: 9, value: 10}, path: []int64{2, 5, 9}},
+			wantErr: ErrCrossShard,
+		},
+		"range": {
+			r:       &rangeReq{testReq: testReq{key: 1, value: 10}, hi: 2},
+			wantErr: ErrCrossShard,
+		},
+		"dependencies on one shard": {
+			r: &dependReq{testReq: testReq{key: 1, value: 10}, deps: []int64{5}},
+		},
+		"dependencies across shards": {
+			r:       &dependReq{testReq: testReq{key: 1, value: 10}, deps: []int64{5, 2}},
+			wantErr: ErrCrossShard,
+		},
+		"GetKey panic": {
+			r:      &panicReq{testReq: testReq{key: 1, value: 10}, method: "GetKey"},
+			panics: true,
+		},
+	}
+
+	for name, tc := range tests {
+		for entry, submit := range entries {
+			t.Run(name+" "+entry, func(t *testing.T) {
+				ss, err := NewShardedSupervisor[int64](SetShardCount(4),
+					SetShardHash(func(k int64) uint64 { return uint64(k) }))
+				if err != nil {
+					t.Fatalf("failed to setup test: %e", err)
+				}
+				go ss.Process()
+				defer ss.Terminate()
+
+				var db mtxMap
+				setupTestItem(tc.r.test(), &db)
+				err = submit(context.Background(), ss, tc.r.(interfaces.Request[int64]))
+				var pe *PanicError
+				switch {
+				case tc.panics:
+					if !errors.As(err, &pe) {
+						t.Errorf("expected PanicError, got: %v", err)
+					}
+				case !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil):
+					t.Errorf("expected error %v, got: %v", tc.wantErr, err)
+				case tc.wantErr == nil:
+					checkDb(t, &db, tc.r.test())
+				case !errors.Is(err, ErrInvalid):
+					t.Errorf("expected error wrapped by %v, got: %v", ErrInvalid, err)
+				}
+			})
+		}
+	}
+}
+
+func Test_ShardedSupervisorOptions(t *testing.T) {
+	tests := map[string]struct {
+		opts    []SupervisorOption
+		wantErr string
+		shards  int
+	}{
+		"default shard count": {
+			shards: -1,
+		},
+		"explicit shard count": {
+			opts:   []SupervisorOption{SetShardCount(3)},
+			shards: 3,
+		},
+		"zero shard count": {
+			opts:    []SupervisorOption{SetShardCount(0)},
+			wantErr: "shard count",
+		},
+		"matching hash": {
+			opts:   []SupervisorOption{SetShardCount(2), SetShardHash(func(k int64) uint64 { return uint64(k) })},
+			shards: 2,
+		},
+		"mismatched hash": {
+			opts:    []SupervisorOption{SetShardHash(func(k string) uint64 { return 0 })},
+			wantErr: "does not match key type",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ss, err := NewShardedSupervisor[int64](tc.opts...)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got: %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.shards > 0 && len(ss.shards) != tc.shards {
+				t.Errorf("expected %d shards, got: %d", tc.shards, len(ss.shards))
+			}
+			if ss.shard(7) != ss.shard(7) {
+				t.Errorf("expected key to consistently map to the same shard")
+			}
+		})
+	}
+}
+
+func Test_DefaultKeyHash(t *testing.T) {
+	type compositeKey struct {
+		tenant string
+		id     int
+	}
+	const shards = 8
+	used := make(map[uint64]bool)
+	for i := int64(0); i < 64; i++ {
+		used[defaultKeyHash(i)%shards] = true
+	}
+	if len(used) != shards {
+		t.Errorf("expected sequential keys to use all %d shards, used: %d", shards, len(used))
+	}
+	if defaultKeyHash("alpha") != defaultKeyHash("alpha") {
+		t.Errorf("expected string hash to be stable")
+	}
+	a, b := compositeKey{"t1", 1}, compositeKey{"t1", 1}
+	if defaultKeyHash(a) != defaultKeyHash(b) {
+		t.Errorf("expected equal composite keys to hash equally")
+	}
+}
@@ -0,0 +1,113 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// SetShedQueueDepth enables shedding begin messages once the queue channel holds at least d
+// messages.  Shed requests fail fast with ErrLoadShed instead of blocking on the queue channel.
+// Zero (the default) disables depth based shedding.
+func SetShedQueueDepth(d uint) SupervisorOption {
+	return func(c *config) error {
+		c.shedDepth = d
+		return nil
+	}
+}
+
+// SetShedQueueLatency enables CoDel style shedding of begin messages based on the time they spend
+// in the queue channel.  Once queue latency has remained above target for a full interval, begin
+// messages are ceased with ErrLoadShed at an increasing rate until latency drops below target.
+// A zero target (the default) disables latency based shedding.
+func SetShedQueueLatency(target, interval time.Duration) SupervisorOption {
+	return func(c *config) error {
+		if target > 0 && interval <= 0 {
+			return fmt.Errorf("shed interval must be positive")
+		}
+		c.shedTarget = target
+		c.shedInterval = interval
+		return nil
+	}
+}
+
+// codel tracks the CoDel (controlled delay) shedding state of the supervisor queue channel.
+type codel struct {
+	target     time.Duration
+	interval   time.Duration
+	firstAbove time.Time // time latency will have remained above target for an interval.
+	dropping   bool
+	dropNext   time.Time
+	count      int
+}
+
+func newCodel(target, interval time.Duration) *codel {
+	if target <= 0 {
+		return nil
+	}
+	return &codel{target: target, interval: interval}
+}
+
+// shed reports whether a begin message which spent latency in the queue channel should be shed.
+// empty indicates the queue channel has drained, which ends shedding regardless of latency.
+func (c *codel) shed(now time.Time, latency time.Duration, empty bool) bool {
+	okToShed := false
+	switch {
+	case latency < c.target || empty:
+		c.firstAbove = time.Time{}
+	case c.firstAbove.IsZero():
+		c.firstAbove = now.Add(c.interval)
+	case !now.Before(c.firstAbove):
+		okToShed = true
+	}
+
+	if c.dropping {
+		if !okToShed {
+			c.dropping = false
+			return false
+		}
+		if now.Before(c.dropNext) {
+			return false
+		}
+		c.count++
+		c.dropNext = c.controlLaw(c.dropNext)
+		return true
+	}
+	if !okToShed {
+		return false
+	}
+
+	// Resume near the previous shedding rate if shedding ended recently.
+	c.dropping = true
+	if c.count > 2 && now.Sub(c.dropNext) < 16*c.interval {
+		c.count -= 2
+	} else {
+		c.count = 1
+	}
+	c.dropNext = c.controlLaw(now)
+	return true
+}
+
+// controlLaw returns the time of the next shed, which shortens as the shed count grows.
+func (c *codel) controlLaw(t time.Time) time.Time {
+	return t.Add(time.Duration(float64(c.interval) / math.Sqrt(float64(c.count))))
+}
+
+// shedLatency ceases begin message m with ErrLoadShed if CoDel shedding is enabled and the queue
+// latency of the message warrants it, returning true if shed.
+func (s *Supervisor[K]) shedLatency(m message[K]) bool {
+	if s.codel == nil {
+		return false
+	}
+	latency := time.Duration(m.getLatency() * float64(time.Second))
+	if !s.codel.shed(time.Now(), latency, len(s.queue) == 0) {
+		return false
+	}
+	s.metrics.Shed(telemetry.Labels{
+		"policy": "latency",
+	})
+	s.cease(m, ErrLoadShed)
+	return true
+}
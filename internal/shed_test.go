@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_codel(t *testing.T) {
+	type dequeue struct {
+		at      time.Duration // offset from start
+		latency time.Duration
+		empty   bool
+		want    bool
+	}
+	tests := map[string][]dequeue{
+		"below target": {
+			{at: 0, latency: 5 * time.Millisecond},
+			{at: 200 * time.Millisecond, latency: 9 * time.Millisecond},
+		},
+		"above target for an interval": {
+			{at: 0, latency: 20 * time.Millisecond},
+			{at: 50 * time.Millisecond, latency: 20 * time.Millisecond},
+			{at: 100 * time.Millisecond, latency: 20 * time.Millisecond, want: true},
+			{at: 150 * time.Millisecond, latency: 20 * time.Millisecond},
+			{at: 200 * time.Millisecond, latency: 20 * time.Millisecond, want: true},
+			// Next shed at 200ms + 100ms/sqrt(2).
+			{at: 260 * time.Millisecond, latency: 20 * time.Millisecond},
+			{at: 271 * time.Millisecond, latency: 20 * time.Millisecond, want: true},
+			{at: 300 * time.Millisecond, latency: 5 * time.Millisecond},
+			{at: 310 * time.Millisecond, latency: 20 * time.Millisecond},
+		},
+		"drained queue resets": {
+			{at: 0, latency: 20 * time.Millisecond},
+			{at: 90 * time.Millisecond, latency: 20 * time.Millisecond, empty: true},
+			{at: 100 * time.Millisecond, latency: 20 * time.Millisecond},
+			{at: 150 * time.Millisecond, latency: 20 * time.Millisecond},
+			{at: 200 * time.Millisecond, latency: 20 * time.Millisecond, want: true},
+		},
+	}
+
+	for name, dequeues := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := newCodel(10*time.Millisecond, 100*time.Millisecond)
+			start := time.Now()
+			for i, d := range dequeues {
+				if got := c.shed(start.Add(d.at), d.latency, d.empty); got != d.want {
+					t.Errorf("dequeue %d at %v: expected shed %v, got %v", i, d.at, d.want, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_SupervisorBackpressure(t *testing.T) {
+	tests := map[string]struct {
+		opts    []SupervisorOption
+		try     bool
+		timeout time.Duration
+		want    []error
+		shed    int64
+	}{
+		"try fails fast": {
+			try:  true,
+			want: []error{ErrQueueFull},
+		},
+		"blocked begin honors context": {
+			timeout: 10 * time.Millisecond,
+			want:    []error{ErrCanceled, context.DeadlineExceeded},
+		},
+		"shed by queue depth": {
+			opts: []SupervisorOption{SetShedQueueDepth(1)},
+			want: []error{ErrLoadShed},
+			shed: 1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, _, li, err := testSetup(append(tc.opts, SetChannelDepth(1))...)
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			defer arbiter.Terminate()
+
+			queued := requestDefs["record1version9"]
+			blocked := requestDefs["record2version10"]
+			setupTestItem(&queued, db)
+			setupTestItem(&blocked, db)
+
+			// Fill the queue channel before the supervisor is processing.
+			ticket := arbiter.Submit(ctx, &queued, func(context.Context) error { return nil })
+
+			reqCtx := ctx
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				reqCtx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+			fn := func(context.Context) error {
+				t.Errorf("expected work not to run")
+				return nil
+			}
+			if tc.try {
+				err = arbiter.TryWithWorker(reqCtx, &blocked, fn)
+			} else {
+				err = arbiter.WithWorker(reqCtx, &blocked, fn)
+			}
+			for _, want := range tc.want {
+				if !errors.Is(err, want) {
+					t.Errorf("expected error to match %v, got: %v", want, err)
+				}
+			}
+			if got := li.SnapMetrics().Counters[at.Shed]; got != tc.shed {
+				t.Errorf("expected %d requests shed, got %d", tc.shed, got)
+			}
+
+			// The queued request completes once the supervisor is processing.
+			go arbiter.Process()
+			if err := ticket.Wait(ctx); err != nil {
+				t.Errorf("expected queued request to succeed, got: %v", err)
+			}
+			waitForGauge(t, li, at.ProcessingMapDepth, 0)
+			checkDb(t, db, &queued)
+			if got := db.get(blocked.key); got != 0 {
+				t.Errorf("expected rejected request not finalized, got %d", got)
+			}
+		})
+	}
+}
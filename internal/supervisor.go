@@ -2,7 +2,10 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/btsomogyi/arbiter/interfaces"
+	"sync"
 	"time"
 
 	"github.com/btsomogyi/arbiter/logging"
@@ -10,29 +13,88 @@ import (
 )
 
 // Supervisor contains the primary channels used for synchronization between Worker and Supervisor.
-type Supervisor struct {
-	queue       chan message
-	terminate   chan struct{}
-	processing  *messageMap
-	waiting     *messageMap
-	metrics     telemetry.Instrumentor
-	logger      logging.Logger
-	pollDone    func()
-	initialized bool
+type Supervisor[K comparable] struct {
+	queue              chan message[K]
+	terminate          chan struct{}
+	shutdown           chan struct{}
+	stopped            chan struct{}
+	shutdownOnce       sync.Once
+	draining           bool
+	processing         *messageMap[K]
+	waiting            *waitingMap[K]
+	admission          []admissionEntry[K]
+	pending            *conflictIndex[K]
+	dependents         *dependentIndex[K]
+	freed              freedKeys[K]
+	waitingTTL         time.Duration
+	expiry             *timerWheel[K]
+	seq                uint64
+	running            int
+	maxProcessing      int
+	preemption         bool
+	revalidate         bool
+	followMode         bool
+	conflictScheduling bool
+	less               func(a, b K) bool
+	maxWork            time.Duration
+	watchdogThreshold  time.Duration
+	watchdogStuck      func(key K, running time.Duration)
+	watchdogRelease    bool
+	watchdogTicker     *time.Ticker
+	executors          int
+	executing          int
+	shedDepth          int
+	codel              *codel
+	limiter            *rateLimiter[K]
+	priority           *priorityQueue[K]
+	backlog            []*completionMessage[K]
+	validating         map[K][]message[K]
+	metrics            telemetry.Instrumentor
+	logger             logging.Logger
+	pollDone           func()
+	initialized        bool
 }
 
 // config contains the adjustable configuraiton of the Supervisor.
 type config struct {
-	channelDepth uint
-	Instrument   telemetry.Instrumentor
-	pollDone     func()
-	logger       logging.Logger
+	channelDepth       uint
+	waitingDepth       uint
+	waitingPolicy      WaitingPolicy
+	waitingTTL         time.Duration
+	maxProcessing      uint
+	preemption         bool
+	revalidate         bool
+	followMode         bool
+	conflictScheduling bool
+	keyOrder           interface{}
+	maxWork            time.Duration
+	watchdogThreshold  time.Duration
+	watchdogStuck      interface{}
+	watchdogRelease    bool
+	executors          uint
+	shedDepth          uint
+	shedTarget         time.Duration
+	shedInterval       time.Duration
+	rate               float64
+	burst              float64
+	keyRate            float64
+	keyBurst           float64
+	ratePolicy         RateLimitPolicy
+	priorityLevels     uint
+	priorityAging      time.Duration
+	shardCount         uint
+	shardHash          interface{}
+	Instrument         telemetry.Instrumentor
+	pollDone           func()
+	logger             logging.Logger
 }
 
 // configuration is the default configuration of the Supervisor.
 var configuration = &config{
-	channelDepth: channelDepth,
-	pollDone:     func() {},
+	channelDepth:  channelDepth,
+	waitingDepth:  waitingDepth,
+	waitingPolicy: NewestWins,
+	pollDone:      func() {},
 }
 
 // A SupervisorOption is a function that modifies the behavior of a Supervisor.
@@ -54,6 +116,59 @@ func SetChannelDepth(d uint) SupervisorOption {
 	}
 }
 
+// SetWaitingDepth sets the maximum number of requests waiting per key.  Depth must be at least one.
+func SetWaitingDepth(d uint) SupervisorOption {
+	return func(c *config) error {
+		if d == 0 {
+			return fmt.Errorf("waiting depth must be at least 1")
+		}
+		c.waitingDepth = d
+		return nil
+	}
+}
+
+// SetWaitingPolicy sets the policy used to order, displace and promote waiting requests.
+func SetWaitingPolicy(p WaitingPolicy) SupervisorOption {
+	return func(c *config) error {
+		if err := p.valid(); err != nil {
+			return err
+		}
+		c.waitingPolicy = p
+		return nil
+	}
+}
+
+// SetMaxProcessing sets the maximum number of in-flight processing entries across all keys.
+// Requests past the maximum hold their key in an admission queue, where they remain subject to
+// supersession, and are admitted in arrival order as processing entries complete.  Zero (the
+// default) places no limit on processing entries.
+func SetMaxProcessing(n uint) SupervisorOption {
+	return func(c *config) error {
+		c.maxProcessing = n
+		return nil
+	}
+}
+
+// SetPreemption enables canceling the work context of an in-flight request when a superseding
+// request arrives for the same key.  The preempted request is not finalized and returns
+// ErrPreempted, and the superseding request is promoted once the preempted work function returns.
+func SetPreemption(enabled bool) SupervisorOption {
+	return func(c *config) error {
+		c.preemption = enabled
+		return nil
+	}
+}
+
+// SetRevalidate enables checking Valid again when a waiting request is promoted, since the
+// in-flight request finalized ahead of it may have invalidated it.  Invalid waiting requests
+// are ceased with the error from Valid, and the next waiting request for the key is promoted.
+func SetRevalidate(enabled bool) SupervisorOption {
+	return func(c *config) error {
+		c.revalidate = enabled
+		return nil
+	}
+}
+
 // SetLogger provides a compatible structured logger for emitting log messages.
 // If not provided, a no-op logger is created during supervisor initialization.
 func SetLogger(l logging.Logger) SupervisorOption {
@@ -72,26 +187,72 @@ func SetPollFunction(f func()) SupervisorOption {
 }
 
 // NewSupervisor returns an initialized arbiter server.
-func NewSupervisor(opts ...SupervisorOption) (*Supervisor, error) {
-	cfg := configuration
-	for _, opt := range opts {
-		if err := opt(cfg); err != nil {
-			return nil, err
-		}
+func NewSupervisor[K comparable](opts ...SupervisorOption) (*Supervisor[K], error) {
+	cfg, err := newConfig(opts...)
+	if err != nil {
+		return nil, err
 	}
-	s := &Supervisor{}
+	if _, err := keyOrder[K](cfg); err != nil {
+		return nil, err
+	}
+	if _, err := watchdogStuck[K](cfg); err != nil {
+		return nil, err
+	}
+	s := &Supervisor[K]{}
 	s.init(cfg)
 
 	return s, nil
 }
 
+// newConfig applies the options to a copy of the default configuration, so options never leak
+// between supervisors.
+func newConfig(opts ...SupervisorOption) (*config, error) {
+	cfg := *configuration
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
 // init initizalizes internal structures and is invoked before processing begins.
-func (s *Supervisor) init(c *config) {
+func (s *Supervisor[K]) init(c *config) {
 	if s.initialized == false {
-		s.processing = newMessageMap()
-		s.waiting = newMessageMap()
-		s.queue = make(chan message, c.channelDepth)
+		s.processing = newMessageMap[K]()
+		s.pending = newConflictIndex[K]()
+		s.dependents = newDependentIndex[K]()
+		s.freed.keys = make(map[K]struct{})
+		s.waiting = newWaitingMap[K](c.waitingDepth, c.waitingPolicy)
+		s.waiting.supersedes = s.callSupersedes
+		s.waitingTTL = c.waitingTTL
+		s.expiry = newTimerWheel[K](expiryTick, expirySlots)
+		s.maxProcessing = int(c.maxProcessing)
+		s.preemption = c.preemption
+		s.revalidate = c.revalidate
+		s.followMode = c.followMode
+		s.conflictScheduling = c.conflictScheduling
+		s.less, _ = keyOrder[K](c)
+		s.processing.ranges.less = s.less
+		s.processing.order.less = s.less
+		s.maxWork = c.maxWork
+		s.watchdogThreshold = c.watchdogThreshold
+		s.watchdogStuck, _ = watchdogStuck[K](c)
+		s.watchdogRelease = c.watchdogRelease
+		s.pending.ranges.less = s.less
+		s.pending.order.less = s.less
+		s.waiting.order.less = s.less
+		s.executors = int(c.executors)
+		s.shedDepth = int(c.shedDepth)
+		s.codel = newCodel(c.shedTarget, c.shedInterval)
+		s.limiter = newRateLimiter[K](c, time.Now())
+		s.priority = newPriorityQueue[K](c.priorityLevels, c.priorityAging, int(c.channelDepth))
+		s.waiting.priority = s.callPriority
+		s.validating = make(map[K][]message[K])
+		s.queue = make(chan message[K], c.channelDepth)
 		s.terminate = make(chan struct{})
+		s.shutdown = make(chan struct{})
+		s.stopped = make(chan struct{})
 		s.metrics = c.Instrument
 		s.pollDone = c.pollDone
 		s.logger = c.logger
@@ -106,81 +267,192 @@ func (s *Supervisor) init(c *config) {
 	s.metrics.QueueChanDepth(0)
 	s.metrics.ProcessingMapDepth(0)
 	s.metrics.WaitingMapDepth(0)
+	s.metrics.AdmissionQueueDepth(0)
+	if s.limiter != nil {
+		s.reportTokens()
+	}
 	s.initialized = true
 }
 
-// Terminate shutdowns the arbiter supervisor goroutine.
-func (s *Supervisor) Terminate() {
+// Terminate shutdowns the arbiter supervisor goroutine immediately.  Any requests still waiting
+// or processing receive ErrShutdown.  Use Shutdown to allow in-flight requests to complete.
+func (s *Supervisor[K]) Terminate() {
 	if s.initialized {
 		close(s.terminate)
 	}
 }
 
+// Shutdown gracefully stops the arbiter supervisor goroutine.  New requests and all requests in
+// the waiting map or admission queue are ceased with ErrShutdown, while in-flight (processing) requests are allowed
+// to send their end messages and be finalized.  Shutdown returns once all in-flight requests have
+// drained and Process has returned, or with the context error if ctx expires first (in which case
+// the supervisor continues draining in the background).
+func (s *Supervisor[K]) Shutdown(ctx context.Context) error {
+	if !s.initialized {
+		return nil
+	}
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+	})
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Process is primary logic loop of Arbiter supervisor goroutine. This should be invoked as soon as
 // message processing should begin. Process will automatically call init() as required.
-func (s *Supervisor) Process() {
+func (s *Supervisor[K]) Process() {
 	if !s.initialized {
 		s.init(configuration)
 	}
+	// Closing stopped releases any workers still awaiting a response.
+	defer close(s.stopped)
+	s.startWatchdog()
+	defer s.stopWatchdog()
 
 	// Receives messages from supervisor queue and dispatches them based on state (begin/end).
-	// Terminates function when supervisor terminate channel is closed. Note messages must be passed
-	// by pointer to processBegin/end to ensure identity is preserved.
+	// Terminates function when supervisor terminate channel is closed, or once drained after
+	// the shutdown channel is closed. Note messages must be passed by pointer to processBegin/end
+	// to ensure identity is preserved.
+	shutdown := s.shutdown
 	for {
 		s.pollDone()
 		select {
 		case m := <-s.queue:
 			s.metrics.DecQueueChanDepth()
-			switch m.(type) {
-			case *beginMessage:
-				m.setLatency()
-				s.processBegin(m)
-				ms := m.getStatus()
-				s.logger.Debug("Supervisor completed begin message processing", []logging.LogTuple{
-					{"key", m.request().GetKey()},
-					{"duration", m.getLatency()},
-					{"state", beginState.String()},
-					{"results", ms.results()},
-					{"waitlist", ms.waitlist()},
-					{"finalizefailure", ms.finalizefailure()},
-				})
-			case *endMessage:
-				m.setLatency()
-				s.processEnd(m)
-				ms := m.getStatus()
-				s.logger.Debug("Supervisor completed end message processing", []logging.LogTuple{
-					{"key", m.request().GetKey()},
-					{"duration", m.getLatency()},
-					{"state", beginState.String()},
-					{"results", ms.results()},
-					{"waitlist", ms.waitlist()},
-					{"finalizefailure", ms.finalizefailure()},
-				})
+			if next := s.prioritize(m); next != nil {
+				s.processMessage(next)
+			}
+		case <-s.priority.ready():
+			// Messages remain buffered in the priority queue.
+			if next := s.prioritize(nil); next != nil {
+				s.processMessage(next)
 			}
+		case <-s.watchdogTick():
+			// In-flight work may have run past the watchdog threshold.
+			s.checkStuck(time.Now())
+		case <-s.expiry.wakeup():
+			// Waiting messages may have expired.
+			s.expireWaiting(time.Now())
+		case <-s.limiter.wakeup():
+			// Rate limit tokens may be available for messages awaiting admission.
+			s.limiter.wake = nil
+			s.admitNext()
+		case <-shutdown:
+			// Only receive shutdown once, draining continues until processing map is empty.
+			shutdown = nil
+			s.beginShutdown()
 		case <-s.terminate:
 			return
 		}
+		s.releaseDependents()
 		s.pollDone()
+		if s.draining && s.processing.length() == 0 && len(s.validating) == 0 {
+			s.logger.Info("Supervisor shutdown drained", nil)
+			return
+		}
+	}
+}
+
+// processMessage dispatches the message based on state (begin/end), or applies the result of an
+// executor request call.
+func (s *Supervisor[K]) processMessage(m message[K]) {
+	switch msg := m.(type) {
+	case *beginMessage[K]:
+		m.setLatency()
+		s.processBegin(m)
+		ms := m.getStatus()
+		s.logger.Debug("Supervisor completed begin message processing", []logging.LogTuple{
+			{"key", m.keyset().key},
+			{"duration", m.getLatency()},
+			{"state", beginState.String()},
+			{"results", ms.results()},
+			{"waitlist", ms.waitlist()},
+			{"finalizefailure", ms.finalizefailure()},
+		})
+	case *endMessage[K]:
+		m.setLatency()
+		s.processEnd(m)
+		ms := m.getStatus()
+		s.logger.Debug("Supervisor completed end message processing", []logging.LogTuple{
+			{"key", m.keyset().key},
+			{"duration", m.getLatency()},
+			{"state", beginState.String()},
+			{"results", ms.results()},
+			{"waitlist", ms.waitlist()},
+			{"finalizefailure", ms.finalizefailure()},
+		})
+	case *completionMessage[K]:
+		s.processCompletion(msg)
+	}
+}
+
+// beginShutdown puts the supervisor in draining mode, and ceases all waiting messages and
+// messages awaiting admission.
+func (s *Supervisor[K]) beginShutdown() {
+	s.draining = true
+	s.logger.Info("Supervisor shutdown requested", []logging.LogTuple{
+		{Field: "processing", Value: s.processing.length()},
+		{Field: "waiting", Value: s.waiting.length() + s.pending.length() + s.dependents.length()},
+		{Field: "admission", Value: len(s.admission)},
+	})
+	for _, m := range append(append(s.waiting.drain(), s.pending.drain()...), s.drainDependents()...) {
+		s.metrics.DecWaitingMapDepth()
+		m.setStatus(msCease)
+		s.pushMessageMetrics(m)
+		m.respond(beginState, ceaseSignal, ErrShutdown)
 	}
+	s.drainAdmission(ErrShutdown)
 }
 
 // processBegin consumes the BeginMessage message and either stores message in waitingMap, responds
 // with 'ceaseSignal' message to worker, or responds with 'proceedSignal' message to worker.
-func (s *Supervisor) processBegin(m message) {
-	_, ok := m.(*beginMessage)
+func (s *Supervisor[K]) processBegin(m message[K]) {
+	_, ok := m.(*beginMessage[K])
 	if !ok {
 		// This is by design an unreachable condition, but left in to detect future package modifications that
 		// may violate that design. Only messages with underlying `beginMessage` type are sent to processBegin().
 		s.logger.DPanic("Non-beginMessage sent to processBegin()", []logging.LogTuple{
-			{Field: "request key", Value: m.request().GetKey()},
+			{Field: "request key", Value: m.keyset().key},
 		})
 	}
 
+	// New messages are not accepted once shutdown has begun.
+	if s.draining {
+		m.setStatus(msCease)
+		s.pushMessageMetrics(m)
+		m.respond(beginState, ceaseSignal, ErrShutdown)
+		return
+	}
+
+	// Shed the message if queue latency is persistently high.
+	if s.shedLatency(m) {
+		return
+	}
+
+	// Hold the message until the keys it depends on are complete.
+	if s.awaitDependencies(m) {
+		return
+	}
+	s.arbitrate(m)
+}
+
+// arbitrate validates begin message m, then arbitrates it for its keys.
+func (s *Supervisor[K]) arbitrate(m message[K]) {
+	// Validate on an executor if configured, continuing once validated.
+	if s.executors > 0 {
+		s.validate(m)
+		return
+	}
+
 	// Check if valid, and reject if not.
-	if err := m.request().Valid(); err != nil {
+	if err := s.callValid(m.request()); err != nil {
 		m.setStatus(msCease)
 		s.pushMessageMetrics(m)
-		m.respond(beginState, ceaseSignal, err)
+		m.respond(beginState, ceaseSignal, wrapOutcome(ErrInvalid, err))
 		return
 	}
 
@@ -190,69 +462,135 @@ func (s *Supervisor) processBegin(m message) {
 
 // enqueMessage checks the processing messageMap for a message with same Request Key and
 // determines if the incoming message supersedes any found (ceased if doesn't supersede).
-// For valid Messages with an active processing entry for that key, check the waiting messageMap
-// to determine if it should be stored (replacing any existing inferior message), or ceaseSignal'd
-// as superseded by the currently waiting message.
-func (s *Supervisor) enqueMessage(m message) {
-	reqKey := m.request().GetKey()
+// For valid Messages with an active processing entry for that key, add the message to the
+// waiting queue for that key, which per the waiting policy may cease either the incoming
+// message or a message it displaces from the waiting queue.  Messages holding several keys, or
+// holding their key in shared mode, and all messages under conflict scheduling, are arbitrated
+// by enquePending.
+func (s *Supervisor[K]) enqueMessage(m message[K]) {
+	reqKey := m.keyset().key
+	s.sequence(m)
+	if lo, hi, ok := m.keyset().span(); ok {
+		s.enqueRange(m, lo, hi)
+		return
+	}
+	if keys := m.keyset().keys; len(keys) > 1 || shared(m) || s.conflictScheduling {
+		s.enquePending(m, keys)
+		return
+	}
 
 	// Check processing map.
 	inProcessMsg, foundProcessing := s.processing.getMessage(reqKey)
-	if !foundProcessing {
+	if !foundProcessing && !s.reserved(reqKey, m) && !s.rangeBlocks(m, []K{reqKey}) {
 		// nothing found active, activate new message immediately.
-		m.setStatus(msProceed)
 		s.activateMessage(m)
 		return
 	}
-	// If new message does not supersede in process message, then new message is redundant, and should
-	// CEASE immediately.
-	if err := m.request().Supersedes(inProcessMsg.request()); err != nil {
-		m.setStatus(msCease)
-		s.pushMessageMetrics(m)
-		m.respond(beginState, ceaseSignal, err)
-		return
+	// Shared messages in process, and messages holding the key as an ancestor, are neither
+	// superseded nor preempted, so the new message waits for every one to complete.
+	if foundProcessing && lockModeAt(inProcessMsg, reqKey) != lockExclusive {
+		foundProcessing = false
 	}
+	if foundProcessing {
+		// If new message does not supersede in process message, then new message is redundant, and
+		// should CEASE immediately.
+		if err := s.callSupersedes(m.request(), inProcessMsg.request()); err != nil {
+			if s.follow(m, inProcessMsg, err) {
+				return
+			}
+			m.setStatus(msCease)
+			s.pushMessageMetrics(m)
+			m.respond(beginState, ceaseSignal, wrapOutcome(ErrSuperseded, err))
+			return
+		}
 
-	// Check Waiting map, and if not found, add new message to waiting map (awaiting completeion
-	// of current in-flight Processing map entry).
-	waitingMsg, waitFound := s.waiting.getMessage(reqKey)
-	if !waitFound {
-		m.setStatus(msWaitlist)
-		s.metrics.IncWaitingMapDepth()
-		s.waiting.add(m)
-		return
+		// A superseded message still awaiting admission has not begun processing, so is replaced
+		// outright, unless other messages are waiting on the key, the policy retains every message
+		// in order, or the message awaiting admission holds other keys.
+		if inProcessMsg.getStatus()&msAdmission != 0 && s.waiting.policy != FIFO && s.waiting.keyLength(reqKey) == 0 &&
+			len(inProcessMsg.keyset().keys) == 1 {
+			s.replaceAdmission(inProcessMsg, m)
+			return
+		}
 	}
 
-	// If the new message does NOT supersede the waiting message, then it is redundant
-	// and is Ceased, leaving the waiting message on the waitlist.
-	if err := m.request().Supersedes(waitingMsg.request()); err != nil {
-		m.setStatus(msCease)
-		s.pushMessageMetrics(m)
-		m.respond(beginState, ceaseSignal, err)
+	// Coalesce with the newest waiting message where its request implements interfaces.Merger.
+	if s.merge(m) {
 		return
 	}
 
-	// Otherwise, new message supersedes waiting message; waiting message is Ceased and
-	// new message put in waiting list in its place.
-	// TODO [BTS]: Enforce Supersedes as reciprical ( a.sup(b) || b.sup(a) == true).  Will
-	// likely require Go2 generic constraints to programatically enforce.
-	err := waitingMsg.request().Supersedes(m.request())
+	// Add to waiting queue (awaiting completion of current in-flight Processing map entry).
+	ceased, by, err := s.waiting.enqueue(m)
+	if ceased != m {
+		s.waitlist(m)
+	}
+	if ceased != m && foundProcessing {
+		s.preempt(inProcessMsg, m)
+	}
+	if ceased == nil {
+		return
+	}
+	kind := ErrSuperseded
+	if ceased != m {
+		s.metrics.DecWaitingMapDepth()
+		kind = ErrDisplaced
+	}
+	if s.follow(ceased, by, err) {
+		return
+	}
+	ceased.setStatus(msCease)
+	s.pushMessageMetrics(ceased)
+	ceased.respond(beginState, ceaseSignal, wrapOutcome(kind, err))
+}
 
-	s.metrics.DecWaitingMapDepth()
-	waitingMsg.setStatus(msCease)
-	s.pushMessageMetrics(waitingMsg)
-	waitingMsg.respond(beginState, ceaseSignal, err)
+// preempt cancels the work of the in-flight processing message when preemption is enabled, so
+// the superseding waiting message is promoted as soon as the preempted worker ends.  Followers of
+// the preempted message follow the superseding message instead.
+func (s *Supervisor[K]) preempt(m, superseding message[K]) {
+	if !s.preemption || m.getStatus()&(msAdmission|msRevalidating|msPreempted|msFinalizing) != 0 {
+		return
+	}
+	m.setStatus(msPreempted)
+	s.transferFollowers(m, superseding)
+	s.logger.Debug("Supervisor preempting in-flight request", []logging.LogTuple{
+		{Field: "key", Value: m.keyset().key},
+	})
+	m.signature().preempt()
+}
 
-	m.setStatus(msWaitlist)
-	s.metrics.IncWaitingMapDepth()
-	s.waiting.add(m)
+// cease responds to begin message m with ceaseSignal and err.
+func (s *Supervisor[K]) cease(m message[K], err error) {
+	s.expiry.remove(m)
+	s.freeKeys(m)
+	m.setStatus(msCease)
+	s.pushMessageMetrics(m)
+	m.respond(beginState, ceaseSignal, err)
+}
 
-	return
+// activateMessage proceeds the message, or queues it for admission if processing is at capacity,
+// earlier messages are already awaiting admission, or rate limit tokens are not yet available.
+// Returns false if the message was ceased by the RateLimitReject policy instead.
+func (s *Supervisor[K]) activateMessage(m message[K]) bool {
+	now := time.Now()
+	if d, scope := s.rateWait(m, now); d > 0 {
+		return s.rateLimited(m, d, scope, now)
+	}
+	if s.atCapacity() || s.admissionBlocked() {
+		s.queueAdmission(m)
+		return true
+	}
+	s.proceedMessage(m)
+	return true
 }
 
-// activateMessage adds message to processing messageMap, notifies worker of message
+// proceedMessage adds message to processing messageMap, notifies worker of message
 // to proceedSignal, and increments counters.
-func (s *Supervisor) activateMessage(m message) {
+func (s *Supervisor[K]) proceedMessage(m message[K]) {
+	if bm, ok := m.(*beginMessage[K]); ok {
+		bm.proceeded = time.Now()
+	}
+	s.takeTokens(m)
+	s.running++
 	s.metrics.IncProcessingMapDepth()
 	s.processing.add(m)
 	m.setStatus(msProceed)
@@ -263,16 +601,34 @@ func (s *Supervisor) activateMessage(m message) {
 // processEnd consumes the EndMessage message and attempts to store successful results to Datastore.
 // Either successSignal or failureSignal result in purging of map data for {id, version} tuple and promoting
 // any waiting versions to processing map (with corresponding send of message).
-func (s *Supervisor) processEnd(m message) {
-	em, ok := m.(*endMessage)
+func (s *Supervisor[K]) processEnd(m message[K]) {
+	em, ok := m.(*endMessage[K])
 	if !ok {
 		// This is by design an unreachable condition, but left in to detect future package modifications that
 		// may violate that design. Only messages with underlying `endMessage` type are sent to processEnd().
 		s.logger.DPanic("Non-endMessage sent to processEnd()", []logging.LogTuple{
-			{Field: "request key", Value: m.request().GetKey()},
+			{Field: "request key", Value: m.keyset().key},
 		})
 	}
 
+	// Requests released by the watchdog no longer hold their keys, so are never finalized.
+	if m.signature().released.Load() {
+		m.setStatus(msFailure)
+		s.pushMessageMetrics(m)
+		m.respond(endState, failureSignal, ErrWorkStuck)
+		return
+	}
+
+	// Preempted requests are never finalized, regardless of the outcome of the work function.
+	if inProcessMsg, found := s.processing.find(m); found &&
+		inProcessMsg.getStatus()&msPreempted != 0 {
+		m.setStatus(msFailure)
+		s.pushMessageMetrics(m)
+		m.respond(endState, failureSignal, ErrPreempted)
+		s.purgeMessage(m)
+		return
+	}
+
 	switch em.signal {
 	case failureSignal:
 		m.setStatus(msFailure)
@@ -280,11 +636,16 @@ func (s *Supervisor) processEnd(m message) {
 		m.respond(endState, failureSignal, nil)
 	case successSignal:
 		m.setStatus(msSuccess)
-		err := m.request().Finalize()
+		if s.executors > 0 {
+			// Response and purge follow once Finalize completes on an executor.
+			s.finalize(m)
+			return
+		}
+		err := s.callFinalize(m.request())
 		if err != nil {
 			m.setStatus(msFinalizeFailure)
 			s.pushMessageMetrics(m)
-			m.respond(endState, failureSignal, err)
+			m.respond(endState, failureSignal, wrapOutcome(ErrFinalizeFailed, err))
 		} else {
 			s.pushMessageMetrics(m)
 			m.respond(endState, successSignal, nil)
@@ -293,7 +654,7 @@ func (s *Supervisor) processEnd(m message) {
 		// This is by design an unreachable condition, but left in to detect future modifications that
 		// may violate that design.  The worker code has no means of setting an invalid value.
 		s.logger.DPanic("Unexpected status sent in end message", []logging.LogTuple{
-			{Field: "request key", Value: m.request().GetKey()},
+			{Field: "request key", Value: m.keyset().key},
 			{Field: "message signal", Value: em.signal},
 		})
 	}
@@ -303,41 +664,101 @@ func (s *Supervisor) processEnd(m message) {
 
 // purgeMessage checks waiting and processing messageMaps for message with same
 // Request key and signals any Messages that are promoted from waiting to processing.
-func (s *Supervisor) purgeMessage(m message) {
-	reqKey := m.request().GetKey()
+func (s *Supervisor[K]) purgeMessage(m message[K]) {
+	// Check validating lists for the begin message of the worker, if found, abandon and return.
+	if s.abandonValidating(m) {
+		return
+	}
 
-	// Check waiting map for exact message, if found, remove and return.
-	if foundWaiting := s.waiting.containsMessage(m); foundWaiting {
-		s.metrics.DecWaitingMapDepth()
-		s.waiting.remove(m)
+	// Check waiting messages for exact message, if found, remove and return.
+	if s.removeWaiting(m) {
 		return
 	}
 
-	// Check processing map for exact message, if found, remove.
-	if foundProcessing := s.processing.containsMessage(m); foundProcessing {
-		s.metrics.DecProcessingMapDepth()
+	// Check processing map for exact message, if found, remove and free its processing slot.
+	if inProcessMsg, foundProcessing := s.processing.find(m); foundProcessing {
+		if inProcessMsg.getStatus()&msAdmission != 0 {
+			s.removeAdmission(m)
+		} else {
+			s.running--
+			s.metrics.DecProcessingMapDepth()
+		}
 		s.processing.remove(m)
-		s.promoteFromWaiting(reqKey)
+		s.releaseMessage(m)
+		s.admitNext()
+	}
+
+}
+
+// removeWaiting removes the exact message from the waiting messages, returning false if not found.
+// Messages awaiting its keys are promoted where now grantable.
+func (s *Supervisor[K]) removeWaiting(m message[K]) bool {
+	// Check messages awaiting dependencies, which hold no keys.
+	if w, found := s.removeDependent(m); found {
+		s.metrics.DecWaitingMapDepth()
+		s.expiry.remove(w)
+		s.freeKeys(m)
+		return true
+	}
+
+	// Check waiting map, if found, promote pending messages which arrived after it.
+	if w, foundWaiting := s.waiting.find(m); foundWaiting {
+		s.metrics.DecWaitingMapDepth()
+		s.waiting.remove(m)
+		s.expiry.remove(w)
+		s.freeKeys(m)
+		s.promotePending(m.keyset().keys)
+		return true
 	}
 
+	// Check pending messages, if found, promote messages its keys were reserved against.
+	if w, found := s.removePending(m); found {
+		s.metrics.DecWaitingMapDepth()
+		s.expiry.remove(w)
+		s.releaseMessage(m)
+		return true
+	}
+	return false
 }
 
-func (s *Supervisor) promoteFromWaiting(reqKey int64) {
+// promoteFromWaiting activates the next message waiting for reqKey, in waiting policy order, if
+// reqKey is free and not reserved by a pending message.  With revalidation enabled, waiting
+// messages no longer valid are ceased until a valid message is found or the waiting queue for
+// reqKey is empty.
+func (s *Supervisor[K]) promoteFromWaiting(reqKey K) {
+	if _, held := s.processing.getMessage(reqKey); held {
+		return
+	}
 	// Check waiting map.
-	if waitingMsg, foundWaiting := s.waiting.getMessage(reqKey); foundWaiting {
-		// Remove from waiting messageMap and activate.
+	for {
+		// The key remains reserved for a pending message which arrived ahead of the next message, or
+		// within a key range held by another message.
+		waitingMsg, foundWaiting := s.waiting.head(reqKey)
+		if !foundWaiting || s.reserved(reqKey, waitingMsg) || s.rangeBlocks(waitingMsg, []K{reqKey}) {
+			return
+		}
+		s.waiting.next(reqKey)
+		s.expiry.remove(waitingMsg)
+		// Removed from waiting queue, so activate if still valid.
 		s.metrics.DecWaitingMapDepth()
-		s.waiting.remove(waitingMsg)
-		s.activateMessage(waitingMsg)
+		if s.revalidate {
+			if s.revalidateHeld(waitingMsg, promoteOp) {
+				return
+			}
+			continue
+		}
+		if s.activateMessage(waitingMsg) {
+			return
+		}
 	}
 }
 
-func (s *Supervisor) pushMessageMetrics(m message) {
+func (s *Supervisor[K]) pushMessageMetrics(m message[K]) {
 	var state string
 	switch m.(type) {
-	case *beginMessage:
+	case *beginMessage[K]:
 		state = beginState.String()
-	case *endMessage:
+	case *endMessage[K]:
 		state = endState.String()
 	}
 	ms := m.getStatus()
@@ -346,6 +767,7 @@ func (s *Supervisor) pushMessageMetrics(m message) {
 		"signal":         ms.results(),
 		"waitlisted":     ms.waitlist(),
 		"finalizefailed": ms.finalizefailure(),
+		"priority":       s.priorityLabel(m.request()),
 	})
 }
 
@@ -354,16 +776,27 @@ func (s *Supervisor) pushMessageMetrics(m message) {
 // signature is generated from this structure, which ensures that even if Worker is
 // passed by value by the consumer which generated the Worker, all messages will
 // continue to have this same unique signature value, disambiguating messages
-// originating from this Worker from messages originating from other Workers.
-func (s *Supervisor) generateWorker(ctx context.Context, r interfaces.Request) (*worker, func()) {
-	w := worker{
-		queue:    s.queue,
-		metrics:  s.metrics,
-		status:   failureSignal,
-		response: make(chan response, channelDepth),
-		done:     make(chan struct{}),
-		request:  r,
-		ctx:      ctx,
+// originating from this Worker from messages originating from other Workers.  ks are the keys of
+// the request (see resolveKeys), unless keyErr is set, in which case the request is ceased with
+// ErrInvalid before its begin message is sent.
+func (s *Supervisor[K]) generateWorker(ctx context.Context, r interfaces.Request[K], ks *keyset[K], keyErr error) (*worker[K], func()) {
+	w := worker[K]{
+		queue:     s.queue,
+		stopped:   s.stopped,
+		metrics:   s.metrics,
+		status:    failureSignal,
+		response:  make(chan response, channelDepth),
+		done:      make(chan struct{}),
+		request:   r,
+		ctx:       ctx,
+		shedDepth: s.shedDepth,
+	}
+	if s.preemption || s.watchdogRelease {
+		w.workCtx, w.cancelWork = context.WithCancel(ctx)
+	}
+	w.keys, w.keyErr = ks, keyErr
+	if w.keyErr == nil {
+		w.key = w.keys.key
 	}
 
 	w.signature = &w
@@ -373,40 +806,59 @@ func (s *Supervisor) generateWorker(ctx context.Context, r interfaces.Request) (
 // WithWorker creates a closure to invoke an Arbiter Worker and handles all
 // communication with the Arbiter Supervisor.  This allows all machinery of
 // interaction between worker and supervisor to be predetermined and private
-// to Arbiter package.
-func (s *Supervisor) WithWorker(ctx context.Context, r interfaces.Request, fn func(context.Context) error) error {
-	w, df := s.generateWorker(ctx, r)
-	defer df()
+// to Arbiter package.  WithWorker blocks until the request completes (see Submit).
+func (s *Supervisor[K]) WithWorker(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) error {
+	return s.WithWorkerOutcome(ctx, r, fn).Err
+}
 
-	s.logger.Debug("WithWorker function entered", []logging.LogTuple{
-		{"key", w.request.GetKey()},
-		{"status", w.status.String()},
-	})
+// runWorker completes the worker begin/work/end protocol once the begin message has been sent,
+// returning the outcome.  df is the deferred function of the worker.
+func (s *Supervisor[K]) runWorker(w *worker[K], df func(), fn func(context.Context) error) Outcome {
+	defer df()
 
-	w.sendBegin()
+	outcome := Outcome{Phase: PhaseBegin}
 	beginResponse := w.recvResponse(beginState, ceaseSignal)
+	outcome.QueueWait = time.Since(w.beginSent)
+	outcome.record(beginResponse)
 
 	s.logger.Debug("WithWorker received beginResponse", []logging.LogTuple{
-		{"key", w.request.GetKey()},
+		{"key", w.key},
 		{"status", w.status.String()},
 		{"response", beginResponse.sig.String()},
 	})
 
 	if beginResponse.sig != proceedSignal {
+		// A follower receives successSignal if the request it followed succeeded.
+		sig := failureSignal
+		if beginResponse.sig == successSignal {
+			sig = successSignal
+		}
 		duration := w.duration()
 		s.metrics.Transactions(duration, telemetry.Labels{
-			"signal": failureSignal.String(),
+			"signal": sig.String(),
 		})
 		s.logger.Debug("WithWorker transaction completed with error", []logging.LogTuple{
-			{"key", w.request.GetKey()},
+			{"key", w.key},
 			{"duration", duration},
 			{"response", beginResponse.sig.String()},
 		})
-		return beginResponse.err
+		return outcome
 	}
 
+	outcome.Phase = PhaseWork
+	w.boundWork(s.workTimeout(w.request))
 	w.workStart = time.Now()
-	if err := fn(ctx); err != nil {
+	if err := s.callWork(w.workContext(), w.request, fn); err != nil {
+		outcome.WorkTime = time.Since(w.workStart)
+		outcome.Signal = failureSignal.outcome()
+		if w.preempted.Load() {
+			outcome.Preempted = true
+			err = ErrPreempted
+		}
+		if w.released.Load() {
+			err = ErrWorkStuck
+		}
+		outcome.Err = wrapOutcome(ErrWorkFailed, err)
 		workDuration := w.workDuration()
 		duration := w.duration()
 		s.metrics.Worktime(workDuration, telemetry.Labels{
@@ -416,13 +868,14 @@ func (s *Supervisor) WithWorker(ctx context.Context, r interfaces.Request, fn fu
 			"signal": failureSignal.String(),
 		})
 		s.logger.Debug("WithWorker transaction completed with closure error", []logging.LogTuple{
-			{"key", w.request.GetKey()},
+			{"key", w.key},
 			{"duration", duration},
 			{"worktime", workDuration},
 			{"response", beginResponse.sig.String()},
 		})
-		return err
+		return outcome
 	}
+	outcome.WorkTime = time.Since(w.workStart)
 
 	w.status = successSignal
 	workDuration := w.workDuration()
@@ -431,12 +884,16 @@ func (s *Supervisor) WithWorker(ctx context.Context, r interfaces.Request, fn fu
 	})
 
 	s.logger.Debug("WithWorker completed provided work function", []logging.LogTuple{
-		{"key", w.request.GetKey()},
+		{"key", w.key},
 		{"status", w.status.String()},
 	})
 
+	outcome.Phase = PhaseEnd
 	w.sendEnd()
 	endResponse := w.recvResponse(endState, failureSignal)
+	outcome.FinalizeTime = time.Since(w.endSent)
+	outcome.record(endResponse)
+	outcome.Preempted = w.preempted.Load() && errors.Is(endResponse.err, ErrPreempted)
 
 	if endResponse.sig != successSignal {
 		duration := w.duration()
@@ -444,16 +901,16 @@ func (s *Supervisor) WithWorker(ctx context.Context, r interfaces.Request, fn fu
 			"signal": failureSignal.String(),
 		})
 		s.logger.Debug("WithWorker transaction completed with error", []logging.LogTuple{
-			{"key", w.request.GetKey()},
+			{"key", w.key},
 			{"duration", duration},
 			{"worktime", workDuration},
 			{"response", endResponse.sig.String()},
 		})
-		return endResponse.err
+		return outcome
 	}
 
 	s.logger.Debug("WithWorker received endResponse", []logging.LogTuple{
-		{"key", w.request.GetKey()},
+		{"key", w.key},
 		{"status", w.status.String()},
 		{"response", endResponse.sig.String()},
 	})
@@ -463,9 +920,9 @@ func (s *Supervisor) WithWorker(ctx context.Context, r interfaces.Request, fn fu
 		"signal": successSignal.String(),
 	})
 	s.logger.Debug("WithWorker transaction completed", []logging.LogTuple{
-		{"key", w.request.GetKey()},
+		{"key", w.key},
 		{"duration", duration},
 		{"worktime", workDuration},
 	})
-	return nil
+	return outcome
 }
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/btsomogyi/arbiter/logging"
 	at "github.com/btsomogyi/arbiter/telemetry"
@@ -69,6 +70,7 @@ var debug = true
 // Test end to end operation of Supervisor
 func Test_Supervisor(t *testing.T) {
 	tests := map[string]struct {
+		opts        []SupervisorOption
 		events      []event
 		wantMetrics at.MetricSnap
 		wantMsgs    histogramSummaries
@@ -722,15 +724,207 @@ func Test_Supervisor(t *testing.T) {
 				"record1version11",
 			},
 		},
+		"three requests two waiting (FIFO depth 2)": {
+			// First request processes while second and third wait for it.  Both waiting requests are
+			// retained in arrival order, and promoted in turn.
+			// c1v9 begin -> queue -> "proceedSignal" response
+			// c1v10 begin -> queue -> c1v10 added to wait queue
+			// c1v11 begin -> queue -> c1v11 added to wait queue (behind c1v10)
+			// c1v9 end -> queue -> "successSignal" response, c1v10 "proceedSignal" response
+			// c1v10 end -> queue -> "successSignal" response, c1v11 "proceedSignal" response
+			// c1v11 end -> queue -> "successSignal" response
+			opts: []SupervisorOption{
+				SetWaitingDepth(2),
+				SetWaitingPolicy(FIFO),
+			},
+			events: []event{
+				{
+					action:     beginRequest,
+					req:        "record1version9",
+					expectWork: true,
+					finishWait: true,
+				},
+				processEvent, // begin message start v9
+				{
+					action:     beginRequest,
+					req:        "record1version10",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v10
+				{
+					action:     beginRequest,
+					req:        "record1version11",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v11
+				{
+					action:     waitRequest,
+					req:        "record1version9",
+					finishWait: true,
+				},
+				processEvent, // end message v9, v10 proceedSignal
+				processEvent, // end message v10, v11 proceedSignal
+				processEvent, // end message v11
+				{
+					action: terminateSupervisor,
+				},
+			},
+			wantMetrics: at.MetricSnap{
+				Gauges: map[at.MetricGauge]int64{
+					at.QueueChanDepth:     0,
+					at.ProcessingMapDepth: 0,
+					at.WaitingMapDepth:    0,
+				},
+			},
+			wantMsgs: histogramSummaries{
+				at.Messages:     6,
+				at.Transactions: 3,
+			},
+			wantDB: []string{
+				"record1version11",
+			},
+		},
+		"stale waiter finalized without revalidation (FIFO depth 2)": {
+			// Without revalidation, a waiting request invalidated by the request promoted ahead of it
+			// still proceeds, and overwrites the newer version.
+			// c1v9 begin -> queue -> "proceedSignal" response
+			// c1v11 begin -> queue -> c1v11 added to wait queue
+			// c1v10 begin -> queue -> c1v10 added to wait queue (behind c1v11)
+			// c1v9 end -> queue -> "successSignal" response, c1v11 "proceedSignal" response
+			// c1v11 end -> queue -> "successSignal" response, c1v10 "proceedSignal" response
+			// c1v10 end -> queue -> "successSignal" response
+			opts: []SupervisorOption{
+				SetWaitingDepth(2),
+				SetWaitingPolicy(FIFO),
+			},
+			events: []event{
+				{
+					action:     beginRequest,
+					req:        "record1version9",
+					expectWork: true,
+					finishWait: true,
+				},
+				processEvent, // begin message start v9
+				{
+					action:     beginRequest,
+					req:        "record1version11",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v11
+				{
+					action:     beginRequest,
+					req:        "record1version10",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v10
+				{
+					action:     waitRequest,
+					req:        "record1version9",
+					finishWait: true,
+				},
+				processEvent, // end message v9, v11 proceedSignal
+				processEvent, // end message v11, v10 proceedSignal
+				processEvent, // end message v10
+				{
+					action: terminateSupervisor,
+				},
+			},
+			wantMetrics: at.MetricSnap{
+				Gauges: map[at.MetricGauge]int64{
+					at.QueueChanDepth:     0,
+					at.ProcessingMapDepth: 0,
+					at.WaitingMapDepth:    0,
+				},
+			},
+			wantMsgs: histogramSummaries{
+				at.Messages:     6,
+				at.Transactions: 3,
+			},
+			wantDB: []string{
+				"record1version10",
+			},
+		},
+		"invalidated waiter ceased on promotion with revalidation (FIFO depth 3)": {
+			// With revalidation, waiting requests are validated again when promoted.  A waiting request
+			// invalidated by the request promoted ahead of it is ceased, and the next candidate promoted.
+			// c1v9 begin -> queue -> "proceedSignal" response
+			// c1v11 begin -> queue -> c1v11 added to wait queue
+			// c1v10 begin -> queue -> c1v10 added to wait queue (behind c1v11)
+			// c1v20 begin -> queue -> c1v20 added to wait queue (behind c1v10)
+			// c1v9 end -> queue -> "successSignal" response, c1v11 "proceedSignal" response
+			// c1v11 end -> queue -> "successSignal" response, c1v10 invalid "ceaseSignal" response,
+			//   c1v20 "proceedSignal" response
+			// c1v10 end -> queue -> no processing entry to purge
+			// c1v20 end -> queue -> "successSignal" response
+			opts: []SupervisorOption{
+				SetWaitingDepth(3),
+				SetWaitingPolicy(FIFO),
+				SetRevalidate(true),
+			},
+			events: []event{
+				{
+					action:     beginRequest,
+					req:        "record1version9",
+					expectWork: true,
+					finishWait: true,
+				},
+				processEvent, // begin message start v9
+				{
+					action:     beginRequest,
+					req:        "record1version11",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v11
+				{
+					action:      beginRequest,
+					req:         "record1version10",
+					expectWork:  false,
+					expectError: ErrInvalidRequest,
+				},
+				processEvent, // begin message waitlist v10
+				{
+					action:     beginRequest,
+					req:        "record1version20",
+					expectWork: true,
+				},
+				processEvent, // begin message waitlist v20
+				{
+					action:     waitRequest,
+					req:        "record1version9",
+					finishWait: true,
+				},
+				processEvent, // end message v9, v11 proceedSignal
+				processEvent, // end message v11, v10 ceaseSignal, v20 proceedSignal
+				processEvent, // end message v10 or v20
+				processEvent, // end message v20 or v10
+				{
+					action: terminateSupervisor,
+				},
+			},
+			wantMetrics: at.MetricSnap{
+				Gauges: map[at.MetricGauge]int64{
+					at.QueueChanDepth:     0,
+					at.ProcessingMapDepth: 0,
+					at.WaitingMapDepth:    0,
+				},
+			},
+			wantMsgs: histogramSummaries{
+				at.Messages:     8,
+				at.Transactions: 4,
+			},
+			wantDB: []string{
+				"record1version20",
+			},
+		},
 	}
 
 	// Begin Test Execution Loop
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			// Setup service side components.
-			arbiter, done, db, ctx, wg, li, err := testSetupWithPolling()
+			arbiter, done, db, ctx, wg, li, err := testSetupWithPolling(tc.opts...)
 			if debug {
-				arbiter, done, db, ctx, wg, li, err = testSetupWithPollingAndSupervisorLogging(t)
+				arbiter, done, db, ctx, wg, li, err = testSetupWithPollingAndSupervisorLogging(t, tc.opts...)
 			}
 			if err != nil {
 				t.Fatalf("failed to setup test: %e", err)
@@ -879,6 +1073,235 @@ func Test_Supervisor(t *testing.T) {
 	}
 }
 
+// Test end to end operation of Supervisor with non-int64 key types.
+func Test_SupervisorGenericKeys(t *testing.T) {
+	type tenantKey struct {
+		tenant   string
+		resource string
+	}
+
+	t.Run("string keys", func(t *testing.T) {
+		runKeyedRequests(t, []*keyedReq[string]{
+			{key: "alpha", value: 1},
+			{key: "beta", value: 1},
+			{key: "alpha", value: 2},
+			{key: "gamma", value: 3},
+		})
+	})
+	t.Run("composite keys", func(t *testing.T) {
+		runKeyedRequests(t, []*keyedReq[tenantKey]{
+			{key: tenantKey{"t1", "r1"}, value: 1},
+			{key: tenantKey{"t1", "r2"}, value: 1},
+			{key: tenantKey{"t2", "r1"}, value: 2},
+			{key: tenantKey{"t1", "r1"}, value: 5},
+		})
+	})
+}
+
+// runKeyedRequests submits each request in order to a fresh Supervisor keyed by K, and confirms
+// that the highest version for each key is the one persisted.
+func runKeyedRequests[K comparable](t *testing.T, reqs []*keyedReq[K]) {
+	t.Helper()
+	li := at.NewLocalInstrumentor()
+	s, err := NewSupervisor[K](SetInstrumentor(li))
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go s.Process()
+	defer s.Terminate()
+
+	db := &keyedStore[K]{db: make(map[K]int64)}
+	want := make(map[K]int64)
+	for _, r := range reqs {
+		r.db = db
+		if r.value > want[r.key] {
+			want[r.key] = r.value
+		}
+		if err := s.WithWorker(context.Background(), r, func(context.Context) error { return nil }); err != nil {
+			t.Errorf("request %v:%d unexpected error: %v", r.key, r.value, err)
+		}
+	}
+
+	for key, value := range want {
+		if got := db.get(key); got != value {
+			t.Errorf("key %v expected: %d got: %d", key, value, got)
+		}
+	}
+	checkMetrics(t, li.SnapMetrics(), at.MetricSnap{
+		Gauges: map[at.MetricGauge]int64{
+			at.QueueChanDepth:     0,
+			at.ProcessingMapDepth: 0,
+			at.WaitingMapDepth:    0,
+		},
+	})
+}
+
+// Test graceful shutdown of Supervisor: waiting and new requests are ceased with ErrShutdown,
+// while in-flight requests complete and are finalized before Shutdown returns.
+func Test_SupervisorShutdown(t *testing.T) {
+	arbiter, db, ctx, wg, li, err := testSetup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+
+	inflight := requestDefs["record1version9"]
+	waiting := requestDefs["record1version10"]
+	late := requestDefs["record2version10"]
+	for _, r := range []*testReq{&inflight, &waiting, &late} {
+		setupTestItem(r, db)
+	}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	results := make(map[string]error)
+	var mtx sync.Mutex
+	submit := func(name string, r *testReq, fn func(context.Context) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := arbiter.WithWorker(ctx, r, fn)
+			mtx.Lock()
+			results[name] = err
+			mtx.Unlock()
+		}()
+	}
+
+	submit("inflight", &inflight, func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	submit("waiting", &waiting, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+
+	// Shutdown cannot complete while the in-flight request is blocked.
+	expired, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := arbiter.Shutdown(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Shutdown to expire with %v, got: %v", context.DeadlineExceeded, err)
+	}
+	waitForGauge(t, li, at.WaitingMapDepth, 0)
+
+	// New requests are ceased while draining.
+	submit("late", &late, func(context.Context) error { return nil })
+	for {
+		mtx.Lock()
+		_, done := results["late"]
+		mtx.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(release)
+	if err := arbiter.Shutdown(ctx); err != nil {
+		t.Errorf("expected Shutdown to drain, got: %v", err)
+	}
+	wg.Wait()
+
+	want := map[string]error{
+		"inflight": nil,
+		"waiting":  ErrShutdown,
+		"late":     ErrShutdown,
+	}
+	for name, expected := range want {
+		if !errors.Is(results[name], expected) {
+			t.Errorf("request %q expected error %v, got: %v", name, expected, results[name])
+		}
+	}
+	checkDb(t, db, &inflight)
+
+	// Supervisor has stopped, so further requests are ceased immediately.
+	if err := arbiter.WithWorker(ctx, &waiting, func(context.Context) error { return nil }); !errors.Is(err, ErrShutdown) {
+		t.Errorf("expected request after shutdown to return %v, got: %v", ErrShutdown, err)
+	}
+}
+
+// Test preemption of in-flight requests by superseding requests.
+func Test_SupervisorPreemption(t *testing.T) {
+	tests := map[string]struct {
+		preemption   bool
+		ignoreCancel bool // work function ignores context cancellation, and completes successfully
+		wantErr      error
+		wantDB       string
+	}{
+		"preempted work canceled": {
+			preemption: true,
+			wantErr:    ErrPreempted,
+			wantDB:     "record1version10",
+		},
+		"preempted work ignoring cancel not finalized": {
+			preemption:   true,
+			ignoreCancel: true,
+			wantErr:      ErrPreempted,
+			wantDB:       "record1version10",
+		},
+		"preemption disabled": {
+			ignoreCancel: true,
+			wantDB:       "record1version10",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			arbiter, db, ctx, wg, li, err := testSetup(SetPreemption(tc.preemption))
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			stale := requestDefs["record1version9"]
+			superseding := requestDefs["record1version10"]
+			setupTestItem(&stale, db)
+			setupTestItem(&superseding, db)
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			var staleErr error
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				staleErr = arbiter.WithWorker(ctx, &stale, func(ctx context.Context) error {
+					close(started)
+					if tc.ignoreCancel {
+						<-release
+						return nil
+					}
+					<-ctx.Done()
+					return ctx.Err()
+				})
+			}()
+			<-started
+
+			var supersedingErr error
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				supersedingErr = arbiter.WithWorker(ctx, &superseding, func(context.Context) error { return nil })
+			}()
+			if tc.ignoreCancel {
+				// Ensure the superseding request is waiting before the stale work completes.
+				waitForGauge(t, li, at.WaitingMapDepth, 1)
+			}
+			close(release)
+			wg.Wait()
+
+			if !errors.Is(staleErr, tc.wantErr) {
+				t.Errorf("expected stale request error %v, got: %v", tc.wantErr, staleErr)
+			}
+			if supersedingErr != nil {
+				t.Errorf("expected superseding request to succeed, got: %v", supersedingErr)
+			}
+			want := requestDefs[tc.wantDB]
+			checkDb(t, db, &want)
+		})
+	}
+}
+
 // TODO: Test Supervisor state at various stages of operations
 
 // Supervisor receives begin message and begins processing.  Confirm
@@ -1059,7 +1482,7 @@ func debugLog(t *testing.T, format string, args ...interface{}) {
 	}
 }
 
-func testSetupWithPollingAndSupervisorLogging(t *testing.T) (*Supervisor, chan struct{}, *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
+func testSetupWithPollingAndSupervisorLogging(t *testing.T, opts ...SupervisorOption) (*Supervisor[int64], chan struct{}, *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
 	done := make(chan struct{})
 	pollDone := func() {
 		done <- struct{}{}
@@ -1071,11 +1494,12 @@ func testSetupWithPollingAndSupervisorLogging(t *testing.T) (*Supervisor, chan s
 		SetPollFunction(pollDone),
 		SetLogger(logger),
 	}
+	supervisorOptions = append(supervisorOptions, opts...)
 	s, m, c, w, f, e := testSetup(supervisorOptions...)
 	return s, done, m, c, w, f, e
 }
 
-func testSetupWithPolling() (*Supervisor, chan struct{}, *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
+func testSetupWithPolling(opts ...SupervisorOption) (*Supervisor[int64], chan struct{}, *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
 	done := make(chan struct{})
 	pollDone := func() {
 		done <- struct{}{}
@@ -1084,17 +1508,18 @@ func testSetupWithPolling() (*Supervisor, chan struct{}, *mtxMap, context.Contex
 	supervisorOptions := []SupervisorOption{
 		SetPollFunction(pollDone),
 	}
+	supervisorOptions = append(supervisorOptions, opts...)
 	s, m, c, w, f, e := testSetup(supervisorOptions...)
 	return s, done, m, c, w, f, e
 }
 
-func testSetup(opts ...SupervisorOption) (*Supervisor, *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
+func testSetup(opts ...SupervisorOption) (*Supervisor[int64], *mtxMap, context.Context, *sync.WaitGroup, *at.LocalInstrumentor, error) {
 	li := at.NewLocalInstrumentor()
 	supervisorOptions := []SupervisorOption{
 		SetInstrumentor(li),
 	}
 	supervisorOptions = append(supervisorOptions, opts...)
-	arbiter, err := NewSupervisor(supervisorOptions...)
+	arbiter, err := NewSupervisor[int64](supervisorOptions...)
 	if err != nil {
 		return nil, nil, nil, nil, nil, err
 	}
@@ -1150,6 +1575,157 @@ func setupTestItem(ti *testReq, db *mtxMap) {
 	}
 }
 
+// newTestBegin creates a beginMessage for request r from its own unique worker, which appends
+// all responses to rec (if provided).
+func newTestBegin[K comparable](r interfaces.Request[K], rec *[]response) *beginMessage[K] {
+	w := &worker[K]{request: r}
+	w.signature = w
+	return &beginMessage[K]{
+		req: r,
+		responseFunc: func(t state, s signal, e error, ms messageStatus) {
+			if rec != nil {
+				*rec = append(*rec, response{state: t, sig: s, err: e, status: ms})
+			}
+		},
+		timestamp: time.Now(),
+		workerSig: w,
+	}
+}
+
+// newTestEnd creates the endMessage with signal sig, from the same worker as begin message b.
+func newTestEnd[K comparable](b *beginMessage[K], sig signal) *endMessage[K] {
+	return &endMessage[K]{
+		req:          b.req,
+		signal:       sig,
+		responseFunc: b.responseFunc,
+		timestamp:    time.Now(),
+		workerSig:    b.workerSig,
+	}
+}
+
+// tableTest is a table test case of a scheduling feature: held requests hold their keys via a Lease
+// while requests, described by R, are submitted in order.
+type tableTest[R any] struct {
+	opts     []SupervisorOption
+	hold     []R      // requests holding keys via a Lease while requests are submitted
+	reqs     []R      // requests submitted in order
+	waiting  int64    // waiting depth once submitted
+	ordered  bool     // each waiting request is enqueued before the next is submitted
+	early    []string // requests completing while the held requests remain held
+	cancel   string   // request canceled while the held requests remain held
+	release  []string // held requests committed, in order, or every held request if unset
+	wantRun  []string // requests whose work function runs, in order
+	wantErrs map[string]error
+}
+
+// runTableTest runs table test case tc, building the named request described by each R with newReq,
+// and returns the LocalInstrumentor of the supervisor once every request completes.
+func runTableTest[R any](t *testing.T, tc tableTest[R], newReq func(R) (string, interfaces.Request[int64])) *at.LocalInstrumentor {
+	t.Helper()
+	arbiter, db, ctx, _, li, err := testSetup(tc.opts...)
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	build := func(d R) (string, interfaces.Request[int64]) {
+		name, r := newReq(d)
+		setupTestItem(r.(testRequest).test(), db)
+		return name, r
+	}
+	var held []string
+	leases := make(map[string]*Lease[int64])
+	for _, d := range tc.hold {
+		name, r := build(d)
+		lease, err := arbiter.Acquire(ctx, r)
+		if err != nil {
+			t.Fatalf("expected Acquire of %s to succeed, got: %v", name, err)
+		}
+		held = append(held, name)
+		leases[name] = lease
+	}
+
+	early := make(map[string]bool)
+	for _, name := range tc.early {
+		early[name] = true
+	}
+	var mtx sync.Mutex
+	var ran []string
+	var waiting int64
+	tickets := make(map[string]*Ticket)
+	for _, d := range tc.reqs {
+		name, r := build(d)
+		tickets[name] = arbiter.Submit(ctx, r, func(context.Context) error {
+			mtx.Lock()
+			ran = append(ran, name)
+			mtx.Unlock()
+			return nil
+		})
+		// Each waiting request is enqueued before the next is submitted, so arrival order holds.
+		if tc.ordered && tc.wantErrs[name] == nil && !early[name] {
+			waiting++
+			waitForGauge(t, li, at.WaitingMapDepth, waiting)
+		}
+	}
+	waitForGauge(t, li, at.WaitingMapDepth, tc.waiting)
+	if tc.cancel != "" {
+		tickets[tc.cancel].Cancel()
+		<-tickets[tc.cancel].Done()
+	}
+	for _, name := range tc.early {
+		select {
+		case <-tickets[name].Done():
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to complete while held requests remain held", name)
+		}
+	}
+	release := tc.release
+	if release == nil {
+		release = held
+	}
+	for _, name := range release {
+		if err := leases[name].Commit(); err != nil {
+			t.Errorf("expected %s to succeed, got: %v", name, err)
+		}
+	}
+
+	for name, ticket := range tickets {
+		<-ticket.Done()
+		want := tc.wantErrs[name]
+		if got := ticket.Result(); !errors.Is(got, want) || (want == nil && got != nil) {
+			t.Errorf("%s: expected error %v, got: %v", name, want, got)
+		}
+	}
+	waitForGauge(t, li, at.ProcessingMapDepth, 0)
+	waitForGauge(t, li, at.WaitingMapDepth, 0)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	if len(ran) != len(tc.wantRun) {
+		t.Fatalf("expected work run for %v, got %v", tc.wantRun, ran)
+	}
+	for i := range tc.wantRun {
+		if ran[i] != tc.wantRun[i] {
+			t.Errorf("expected work run for %v, got %v", tc.wantRun, ran)
+			break
+		}
+	}
+	return li
+}
+
+// waitForGauge polls the LocalInstrumentor until gauge reaches value, failing the test after one second.
+func waitForGauge(t testing.TB, li *at.LocalInstrumentor, gauge at.MetricGauge, value int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for li.SnapMetrics().Gauges[gauge] != value {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for gauge %s to reach %d", gauge, value)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
 // Check the summary of messages processed and transactions completed.
 func checkMessages(t *testing.T, result histogramSummaries, expect histogramSummaries) {
 	t.Helper()
@@ -1196,15 +1772,25 @@ type testReq struct {
 	finalize func() error
 }
 
+// testRequest is implemented by testReq, and by test requests embedding it.
+type testRequest interface {
+	test() *testReq
+}
+
+func (t *testReq) test() *testReq {
+	return t
+}
+
 func (t *testReq) GetKey() int64 {
 	return t.key
 }
 
-func (t *testReq) Supersedes(o interfaces.Request) error {
-	otherTestReq, ok := o.(*testReq)
+func (t *testReq) Supersedes(o interfaces.Request[int64]) error {
+	other, ok := o.(testRequest)
 	if !ok {
 		return fmt.Errorf("Failed to cast request as 'testReq'")
 	}
+	otherTestReq := other.test()
 	if t.value > otherTestReq.value {
 		return nil
 	}
@@ -1275,3 +1861,55 @@ func (tw testWriter) Write(p []byte) (n int, err error) {
 	tw.t.Log(string(p))
 	return len(p), nil
 }
+
+// keyedReq implements arbiter.Request for tests of non-int64 key types.
+type keyedReq[K comparable] struct {
+	key   K
+	value int64
+	db    *keyedStore[K]
+}
+
+func (k *keyedReq[K]) GetKey() K {
+	return k.key
+}
+
+func (k *keyedReq[K]) Supersedes(o interfaces.Request[K]) error {
+	other, ok := o.(*keyedReq[K])
+	if !ok {
+		return fmt.Errorf("Failed to cast request as 'keyedReq'")
+	}
+	if k.value > other.value {
+		return nil
+	}
+	return fmt.Errorf("%w: %v:%d superseded by %v:%d", ErrSupersededRequest, k.key, k.value, other.key, other.value)
+}
+
+func (k *keyedReq[K]) Valid() error {
+	if k.value > k.db.get(k.key) {
+		return nil
+	}
+	return fmt.Errorf("%w: test item %v:%d not valid", ErrInvalidRequest, k.key, k.value)
+}
+
+func (k *keyedReq[K]) Finalize() error {
+	k.db.set(k.key, k.value)
+	return nil
+}
+
+// keyedStore acts as persistent storage for keyedReq during testing.
+type keyedStore[K comparable] struct {
+	db  map[K]int64
+	mtx sync.Mutex
+}
+
+func (m *keyedStore[K]) set(k K, v int64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.db[k] = v
+}
+
+func (m *keyedStore[K]) get(k K) int64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.db[k]
+}
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"context"
+	"github.com/btsomogyi/arbiter/interfaces"
+
+	"github.com/btsomogyi/arbiter/logging"
+	"github.com/btsomogyi/arbiter/telemetry"
+)
+
+// Ticket tracks a request submitted to the Supervisor.  The begin/work/end cycle of the request
+// runs on a goroutine managed by the Supervisor, and its outcome is available from the Ticket
+// once Done is closed.
+type Ticket struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	outcome Outcome
+}
+
+// Done returns a channel that is closed once the request has completed.
+func (t *Ticket) Done() <-chan struct{} {
+	return t.done
+}
+
+// Wait blocks until the request has completed, returning its outcome, or returns the context
+// error if ctx expires first (the request continues regardless).
+func (t *Ticket) Wait(ctx context.Context) error {
+	select {
+	case <-t.done:
+		return t.outcome.Err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Result returns the outcome of the request without blocking, or ErrPending if the request has
+// not yet completed.
+func (t *Ticket) Result() error {
+	select {
+	case <-t.done:
+		return t.outcome.Err
+	default:
+		return ErrPending
+	}
+}
+
+// Cancel cancels the context of the request.  A request not yet proceeded is ceased with the
+// context error, while the context passed to a running work function is canceled.
+func (t *Ticket) Cancel() {
+	t.cancel()
+}
+
+// Submit arbitrates request r asynchronously, running fn if the request is permitted to proceed,
+// and returns a Ticket to collect the outcome.  The outcome is identical to that returned by
+// WithWorker.  The begin message is sent before Submit returns, so requests submitted in
+// sequence by one goroutine arrive at the Supervisor in submission order.  Submit blocks while
+// the queue channel is full, until the begin message is sent or ctx ends.
+func (s *Supervisor[K]) Submit(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) *Ticket {
+	ks, keyErr := s.resolveKeys(r)
+	return s.submit(ctx, r, ks, keyErr, fn, false)
+}
+
+// TryWithWorker arbitrates request r as WithWorker, but fails fast with ErrQueueFull rather than
+// blocking if the supervisor queue channel is full.
+func (s *Supervisor[K]) TryWithWorker(ctx context.Context, r interfaces.Request[K], fn func(context.Context) error) error {
+	ks, keyErr := s.resolveKeys(r)
+	t := s.submit(ctx, r, ks, keyErr, fn, true)
+	<-t.done
+	return t.outcome.Err
+}
+
+// submit sends the begin message of request r, with keys ks or key resolution error keyErr (see
+// generateWorker), and runs the remainder of the worker protocol on a new goroutine.  If try is
+// set, the begin message is only sent if the queue channel has room.
+func (s *Supervisor[K]) submit(ctx context.Context, r interfaces.Request[K], ks *keyset[K], keyErr error, fn func(context.Context) error, try bool) *Ticket {
+	ctx, cancel := context.WithCancel(ctx)
+	t := &Ticket{
+		done:   make(chan struct{}),
+		cancel: cancel,
+	}
+	w, df := s.generateWorker(ctx, r, ks, keyErr)
+
+	s.logger.Debug("WithWorker function entered", []logging.LogTuple{
+		{Field: "key", Value: w.key},
+		{Field: "status", Value: w.status.String()},
+	})
+
+	if err := w.sendBegin(try); err != nil {
+		df()
+		cancel()
+		s.metrics.Transactions(w.duration(), telemetry.Labels{
+			"signal": failureSignal.String(),
+		})
+		t.outcome = Outcome{Phase: PhaseBegin, Signal: ceaseSignal.outcome(), Err: err}
+		close(t.done)
+		return t
+	}
+	go func() {
+		defer close(t.done)
+		defer cancel()
+		t.outcome = s.runWorker(w, df, fn)
+	}()
+	return t
+}
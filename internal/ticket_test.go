@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+func Test_SubmitTicket(t *testing.T) {
+	arbiter, db, ctx, _, li, err := testSetup()
+	if err != nil {
+		t.Fatalf("failed to setup test: %e", err)
+	}
+	go arbiter.Process()
+	defer arbiter.Terminate()
+
+	inflight := requestDefs["record1version9"]
+	waiting := requestDefs["record1version10"]
+	other := requestDefs["record2version10"]
+	for _, r := range []*testReq{&inflight, &waiting, &other} {
+		setupTestItem(r, db)
+	}
+
+	release := make(chan struct{})
+	inflightTicket := arbiter.Submit(ctx, &inflight, func(context.Context) error {
+		<-release
+		return nil
+	})
+	waitForGauge(t, li, at.ProcessingMapDepth, 1)
+
+	// Outcome is unavailable while the request is in-flight.
+	if err := inflightTicket.Result(); !errors.Is(err, ErrPending) {
+		t.Errorf("expected Result %v while in-flight, got: %v", ErrPending, err)
+	}
+	expired, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := inflightTicket.Wait(expired); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Wait to expire with %v, got: %v", context.DeadlineExceeded, err)
+	}
+
+	// Requests for other keys are not blocked by the in-flight request.
+	if err := arbiter.Submit(ctx, &other, func(context.Context) error { return nil }).Wait(ctx); err != nil {
+		t.Errorf("expected request for other key to succeed, got: %v", err)
+	}
+
+	// Canceling a waiting request ceases it with the context error.
+	waitingTicket := arbiter.Submit(ctx, &waiting, func(context.Context) error { return nil })
+	waitForGauge(t, li, at.WaitingMapDepth, 1)
+	waitingTicket.Cancel()
+	<-waitingTicket.Done()
+	if err := waitingTicket.Result(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected canceled request to return %v, got: %v", context.Canceled, err)
+	}
+
+	close(release)
+	if err := inflightTicket.Wait(ctx); err != nil {
+		t.Errorf("expected in-flight request to succeed, got: %v", err)
+	}
+	if err := inflightTicket.Result(); err != nil {
+		t.Errorf("expected Result of completed request to succeed, got: %v", err)
+	}
+	checkDb(t, db, &inflight)
+	checkDb(t, db, &other)
+}
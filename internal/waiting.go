@@ -0,0 +1,262 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+)
+
+// waitingDepth is the default number of waiting entries retained per key.
+const waitingDepth = 1
+
+// WaitingPolicy determines how messages are admitted to, displaced from, and promoted out of
+// the per-key waiting queue.
+type WaitingPolicy int
+
+// Set of WaitingPolicy values.  NewestWins retains only the most recent requests (each must
+// supersede the last one waiting), displacing the oldest when the queue is full.  FIFO retains
+// every valid request in arrival order (behind waiting requests of equal or higher priority),
+// ceasing newcomers when the queue is full.  SupersedesOrder keeps requests sorted by
+// Supersedes, displacing the most superseded entry when the queue is full; where neither of two
+// requests supersedes the other, the higher priority request is retained.  All policies promote
+// from the head of the queue.
+const (
+	NewestWins      WaitingPolicy = iota // NewestWins is the default (single entry) waiting behavior.
+	FIFO                                 // FIFO promotes every waiting request in arrival order.
+	SupersedesOrder                      // SupersedesOrder promotes waiting requests in Supersedes order.
+)
+
+func (p WaitingPolicy) String() string {
+	if p.valid() != nil {
+		return fmt.Sprintf("WaitingPolicy(%d)", int(p))
+	}
+	return [...]string{"NewestWins", "FIFO", "SupersedesOrder"}[p]
+}
+
+// valid confirms the policy is one of the defined WaitingPolicy values.
+func (p WaitingPolicy) valid() error {
+	if p < NewestWins || p > SupersedesOrder {
+		return fmt.Errorf("unknown waiting policy %d", p)
+	}
+	return nil
+}
+
+// waitingMap stores, for each key, the bounded queue of messages awaiting completion of the
+// processing entry for that key.  The head of each queue is the next message to be promoted.
+type waitingMap[K comparable] struct {
+	queues map[K][]message[K]
+	order  keyIndex[K]
+	depth  int
+	policy WaitingPolicy
+	count  int
+	// supersedes invokes Request.Supersedes, allowing the Supervisor to recover panics.
+	supersedes func(r, o interfaces.Request[K]) error
+	// priority returns the priority of a request, used as a tie-breaker between requests.
+	priority func(r interfaces.Request[K]) int
+}
+
+func newWaitingMap[K comparable](depth uint, policy WaitingPolicy) *waitingMap[K] {
+	return &waitingMap[K]{
+		queues: make(map[K][]message[K]),
+		depth:  int(depth),
+		policy: policy,
+		supersedes: func(r, o interfaces.Request[K]) error {
+			return r.Supersedes(o)
+		},
+		priority: func(r interfaces.Request[K]) int {
+			if p, ok := r.(interfaces.Prioritized); ok {
+				return p.Priority()
+			}
+			return 0
+		},
+	}
+}
+
+// enqueue adds the message to the waiting queue for its key according to the waiting policy.
+// If a message must be ceased as a result (either the incoming message, or a waiting message
+// displaced by it) that message is returned along with the message superseding it (nil if the
+// queue is full) and the error to cease it with.
+func (wm *waitingMap[K]) enqueue(m message[K]) (message[K], message[K], error) {
+	key := m.keyset().key
+	queue := wm.queues[key]
+
+	switch wm.policy {
+	case FIFO:
+		if len(queue) >= wm.depth {
+			return m, nil, ErrWaitingQueueFull
+		}
+		// Requests are promoted in arrival order, except ahead of lower priority requests.
+		p := wm.priority(m.request())
+		i := len(queue)
+		for i > 0 && wm.priority(queue[i-1].request()) < p {
+			i--
+		}
+		queue = append(queue, nil)
+		copy(queue[i+1:], queue[i:])
+		queue[i] = m
+	case SupersedesOrder:
+		// Find the first waiting entry the new message does not supersede, and insert before it.
+		i := 0
+		for ; i < len(queue); i++ {
+			err := wm.supersedes(m.request(), queue[i].request())
+			if err == nil {
+				continue
+			}
+			// Neither request supersedes the other, so the new message is redundant, unless it has
+			// higher priority, in which case it takes the place of the waiting message.
+			if wm.supersedes(queue[i].request(), m.request()) != nil {
+				if wm.priority(m.request()) <= wm.priority(queue[i].request()) {
+					return m, queue[i], err
+				}
+				displaced := queue[i]
+				queue[i] = m
+				return displaced, m, wm.supersedes(displaced.request(), m.request())
+			}
+			break
+		}
+		queue = append(queue, nil)
+		copy(queue[i+1:], queue[i:])
+		queue[i] = m
+	default:
+		// If the new message does NOT supersede the newest waiting message, then it is redundant
+		// and is Ceased, leaving the waiting messages on the waitlist.
+		if len(queue) > 0 {
+			if err := wm.supersedes(m.request(), queue[len(queue)-1].request()); err != nil {
+				return m, queue[len(queue)-1], err
+			}
+		}
+		queue = append(queue, m)
+	}
+
+	var displaced, by message[K]
+	var err error
+	if len(queue) > wm.depth {
+		// Head of queue is the most superseded (or oldest) entry, so is displaced.
+		// TODO [BTS]: Enforce Supersedes as reciprical ( a.sup(b) || b.sup(a) == true).
+		displaced, by = queue[0], queue[1]
+		err = wm.supersedes(displaced.request(), by.request())
+		queue = queue[1:]
+	}
+	if _, ok := wm.queues[key]; !ok {
+		wm.order.add(key)
+	}
+	wm.queues[key] = queue
+	wm.count++
+	if displaced != nil {
+		wm.count--
+	}
+	return displaced, by, err
+}
+
+// next removes and returns the message at the head of the waiting queue for key.
+func (wm *waitingMap[K]) next(key K) (message[K], bool) {
+	queue, ok := wm.queues[key]
+	if !ok || len(queue) == 0 {
+		return nil, false
+	}
+	m := queue[0]
+	wm.setQueue(key, queue[1:])
+	wm.count--
+	return m, true
+}
+
+// head returns the message at the head of the waiting queue for key, which is promoted next.
+func (wm *waitingMap[K]) head(key K) (message[K], bool) {
+	queue := wm.queues[key]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	return queue[0], true
+}
+
+// newest returns the message at the tail of the waiting queue for key, which is promoted last.
+func (wm *waitingMap[K]) newest(key K) (message[K], bool) {
+	queue := wm.queues[key]
+	if len(queue) == 0 {
+		return nil, false
+	}
+	return queue[len(queue)-1], true
+}
+
+// drain removes and returns all waiting messages, leaving the waiting map empty.
+func (wm *waitingMap[K]) drain() []message[K] {
+	var drained []message[K]
+	for key, queue := range wm.queues {
+		drained = append(drained, queue...)
+		delete(wm.queues, key)
+	}
+	wm.order.reset()
+	wm.count = 0
+	return drained
+}
+
+// containsMessage determines if the exact message is stored in waitingMap.
+func (wm *waitingMap[K]) containsMessage(m message[K]) bool {
+	return wm.indexOf(m) >= 0
+}
+
+// find returns the stored message which is the exact message m.
+func (wm *waitingMap[K]) find(m message[K]) (message[K], bool) {
+	i := wm.indexOf(m)
+	if i < 0 {
+		return nil, false
+	}
+	return wm.queues[m.keyset().key][i], true
+}
+
+// remove removes the exact message from the waiting queue for its key.
+func (wm *waitingMap[K]) remove(m message[K]) {
+	i := wm.indexOf(m)
+	if i < 0 {
+		return
+	}
+	key := m.keyset().key
+	queue := wm.queues[key]
+	wm.setQueue(key, append(queue[:i:i], queue[i+1:]...))
+	wm.count--
+}
+
+// keysIn returns the keys within [lo, hi) with waiting messages, in key order.
+func (wm *waitingMap[K]) keysIn(lo, hi K) []K {
+	return wm.order.in(wm.queues, lo, hi)
+}
+
+// keyLength returns the number of messages waiting for key.
+func (wm *waitingMap[K]) keyLength(key K) int {
+	return len(wm.queues[key])
+}
+
+// length returns the number of messages waiting for all keys.
+func (wm *waitingMap[K]) length() int {
+	return wm.count
+}
+
+// dump returns all waiting requests for debugging purposes.
+func (wm *waitingMap[K]) dump() []interfaces.Request[K] {
+	var dump []interfaces.Request[K]
+	for _, queue := range wm.queues {
+		for _, m := range queue {
+			dump = append(dump, m.request())
+		}
+	}
+	return dump
+}
+
+func (wm *waitingMap[K]) indexOf(m message[K]) int {
+	for i, w := range wm.queues[m.keyset().key] {
+		if w.same(m) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setQueue stores the queue for key, removing the key entirely once its queue is empty.
+func (wm *waitingMap[K]) setQueue(key K, queue []message[K]) {
+	if len(queue) == 0 {
+		delete(wm.queues, key)
+		wm.order.remove(key)
+		return
+	}
+	wm.queues[key] = queue
+}
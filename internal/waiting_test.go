@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_WaitingMap(t *testing.T) {
+	tests := map[string]struct {
+		depth  uint
+		policy WaitingPolicy
+		// arrivals are the versions (for key 1) enqueued in order.
+		arrivals []int64
+		// wantCeased are the versions ceased during enqueue, in order.
+		wantCeased []int64
+		wantErrs   []error
+		// wantOrder are the versions promoted from the waiting map, in order.
+		wantOrder []int64
+	}{
+		"newest wins depth 1 superseding": {
+			depth:      1,
+			policy:     NewestWins,
+			arrivals:   []int64{10, 11},
+			wantCeased: []int64{10},
+			wantErrs:   []error{ErrSupersededRequest},
+			wantOrder:  []int64{11},
+		},
+		"newest wins depth 1 redundant": {
+			depth:      1,
+			policy:     NewestWins,
+			arrivals:   []int64{11, 10},
+			wantCeased: []int64{10},
+			wantErrs:   []error{ErrSupersededRequest},
+			wantOrder:  []int64{11},
+		},
+		"newest wins depth 2 displaces oldest": {
+			depth:      2,
+			policy:     NewestWins,
+			arrivals:   []int64{10, 11, 12},
+			wantCeased: []int64{10},
+			wantErrs:   []error{ErrSupersededRequest},
+			wantOrder:  []int64{11, 12},
+		},
+		"fifo retains arrival order": {
+			depth:     3,
+			policy:    FIFO,
+			arrivals:  []int64{12, 10, 11},
+			wantOrder: []int64{12, 10, 11},
+		},
+		"fifo full ceases newcomer": {
+			depth:      2,
+			policy:     FIFO,
+			arrivals:   []int64{10, 11, 12},
+			wantCeased: []int64{12},
+			wantErrs:   []error{ErrWaitingQueueFull},
+			wantOrder:  []int64{10, 11},
+		},
+		"supersedes order sorts arrivals": {
+			depth:     3,
+			policy:    SupersedesOrder,
+			arrivals:  []int64{12, 10, 11},
+			wantOrder: []int64{10, 11, 12},
+		},
+		"supersedes order ceases duplicate": {
+			depth:      3,
+			policy:     SupersedesOrder,
+			arrivals:   []int64{12, 10, 12},
+			wantCeased: []int64{12},
+			wantErrs:   []error{ErrSupersededRequest},
+			wantOrder:  []int64{10, 12},
+		},
+		"supersedes order full displaces most superseded": {
+			depth:      2,
+			policy:     SupersedesOrder,
+			arrivals:   []int64{12, 11, 10, 13},
+			wantCeased: []int64{10, 11},
+			wantErrs:   []error{ErrSupersededRequest, ErrSupersededRequest},
+			wantOrder:  []int64{12, 13},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			wm := newWaitingMap[int64](tc.depth, tc.policy)
+			var ceased []int64
+			var errs []error
+			for _, v := range tc.arrivals {
+				m := newTestBegin[int64](&testReq{key: 1, value: v}, nil)
+				if c, _, err := wm.enqueue(m); c != nil {
+					ceased = append(ceased, c.request().(*testReq).value)
+					errs = append(errs, err)
+				}
+			}
+			if diff := cmp.Diff(tc.wantCeased, ceased); diff != "" {
+				t.Errorf("ceased mismatch (-want +got):\n%s", diff)
+			}
+			for i, err := range errs {
+				if i < len(tc.wantErrs) && !errors.Is(err, tc.wantErrs[i]) {
+					t.Errorf("ceased %d expected error %v, got: %v", i, tc.wantErrs[i], err)
+				}
+			}
+			if wm.length() != len(tc.wantOrder) {
+				t.Errorf("expected waiting length %d, got: %d", len(tc.wantOrder), wm.length())
+			}
+			var order []int64
+			for m, ok := wm.next(1); ok; m, ok = wm.next(1) {
+				order = append(order, m.request().(*testReq).value)
+			}
+			if diff := cmp.Diff(tc.wantOrder, order); diff != "" {
+				t.Errorf("promotion order mismatch (-want +got):\n%s", diff)
+			}
+			if wm.length() != 0 {
+				t.Errorf("expected empty waiting map, got length: %d", wm.length())
+			}
+		})
+	}
+}
+
+func Test_WaitingMapRemove(t *testing.T) {
+	wm := newWaitingMap[int64](3, FIFO)
+	first := newTestBegin[int64](&testReq{key: 1, value: 10}, nil)
+	second := newTestBegin[int64](&testReq{key: 1, value: 11}, nil)
+	wm.enqueue(first)
+	wm.enqueue(second)
+
+	wm.remove(first)
+	if wm.containsMessage(first) {
+		t.Errorf("removed message still contained in waiting map")
+	}
+	if !wm.containsMessage(second) {
+		t.Errorf("remaining message not contained in waiting map")
+	}
+	if m, ok := wm.next(1); !ok || m != second {
+		t.Errorf("expected remaining message to be promoted")
+	}
+	if _, ok := wm.queues[1]; ok {
+		t.Errorf("expected empty queue to be removed from waiting map")
+	}
+}
+
+func Test_WaitingPolicyString(t *testing.T) {
+	tests := map[string]struct {
+		p    WaitingPolicy
+		want string
+	}{
+		"defined":   {p: SupersedesOrder, want: "SupersedesOrder"},
+		"negative":  {p: -1, want: "WaitingPolicy(-1)"},
+		"undefined": {p: SupersedesOrder + 1, want: "WaitingPolicy(3)"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.p.String(); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
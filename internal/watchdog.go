@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	"github.com/btsomogyi/arbiter/logging"
+)
+
+// SetMaxWorkDuration sets the maximum duration of the work of a request, after which the context
+// passed to its work function (or the Context of its Lease) ends.  Requests implementing
+// interfaces.Timed may override it.  Zero (the default) places no limit on work duration.
+func SetMaxWorkDuration(d time.Duration) SupervisorOption {
+	return func(c *config) error {
+		if d < 0 {
+			return fmt.Errorf("max work duration must not be negative")
+		}
+		c.maxWork = d
+		return nil
+	}
+}
+
+// SetWatchdog enables a watchdog reporting processing requests whose work has run longer than
+// threshold, such as work ignoring its context, which otherwise holds its keys indefinitely.
+// Each stuck request is logged as a warning, counted in the StuckKeys gauge until it completes,
+// and reported once to stuck, if not nil.  stuck is invoked on the supervisor goroutine, so must
+// not block.
+func SetWatchdog[K comparable](threshold time.Duration, stuck func(key K, running time.Duration)) SupervisorOption {
+	return func(c *config) error {
+		if threshold <= 0 {
+			return fmt.Errorf("watchdog threshold must be positive")
+		}
+		c.watchdogThreshold = threshold
+		if stuck != nil {
+			c.watchdogStuck = stuck
+		}
+		return nil
+	}
+}
+
+// SetWatchdogRelease enables the watchdog (see SetWatchdog) to release the keys of stuck requests,
+// canceling their work context, so requests waiting for those keys proceed.  Released requests are
+// never finalized, and return ErrWorkStuck once their work function returns.
+func SetWatchdogRelease(enabled bool) SupervisorOption {
+	return func(c *config) error {
+		c.watchdogRelease = enabled
+		return nil
+	}
+}
+
+// watchdogStuck returns the stuck callback configured by SetWatchdog, or an error if it does not
+// match the key type of the supervisor.
+func watchdogStuck[K comparable](c *config) (func(key K, running time.Duration), error) {
+	if c.watchdogStuck == nil {
+		return nil, nil
+	}
+	stuck, ok := c.watchdogStuck.(func(key K, running time.Duration))
+	if !ok {
+		return nil, fmt.Errorf("watchdog callback %T does not match key type", c.watchdogStuck)
+	}
+	return stuck, nil
+}
+
+// workTimeout returns the maximum work duration of request r, which is its own if it implements
+// interfaces.Timed, otherwise that of the supervisor.
+func (s *Supervisor[K]) workTimeout(r interfaces.Request[K]) time.Duration {
+	if d := s.callWorkTimeout(r); d > 0 {
+		return d
+	}
+	return s.maxWork
+}
+
+// startWatchdog starts the watchdog ticker, checking for stuck work twice per threshold.
+func (s *Supervisor[K]) startWatchdog() {
+	if s.watchdogThreshold <= 0 {
+		return
+	}
+	s.watchdogTicker = time.NewTicker(s.watchdogThreshold / 2)
+	s.metrics.StuckKeys(0)
+}
+
+// stopWatchdog stops the watchdog ticker, if started.
+func (s *Supervisor[K]) stopWatchdog() {
+	if s.watchdogTicker != nil {
+		s.watchdogTicker.Stop()
+	}
+}
+
+// watchdogTick returns the channel signaling the watchdog to check for stuck work, or nil if the
+// watchdog is disabled.
+func (s *Supervisor[K]) watchdogTick() <-chan time.Time {
+	if s.watchdogTicker == nil {
+		return nil
+	}
+	return s.watchdogTicker.C
+}
+
+// checkStuck reports processing messages whose work has run past the watchdog threshold, releasing
+// them if watchdog release is enabled, and updates the StuckKeys gauge.
+func (s *Supervisor[K]) checkStuck(now time.Time) {
+	var stuck int64
+	for _, m := range s.processing.messages() {
+		bm, ok := m.(*beginMessage[K])
+		if !ok || bm.proceeded.IsZero() || m.getStatus()&(msAdmission|msFinalizing) != 0 {
+			continue
+		}
+		running := now.Sub(bm.proceeded)
+		if running < s.watchdogThreshold {
+			continue
+		}
+		if !bm.stuck {
+			bm.stuck = true
+			s.logger.Warn("Supervisor detected stuck work", []logging.LogTuple{
+				{Field: "key", Value: m.keyset().key},
+				{Field: "running", Value: running},
+			})
+			if s.watchdogStuck != nil {
+				_ = s.guard("watchdog", m.request(), func() error {
+					s.watchdogStuck(m.keyset().key, running)
+					return nil
+				})
+			}
+		}
+		if s.watchdogRelease {
+			s.forceRelease(m)
+			continue
+		}
+		stuck++
+	}
+	s.metrics.StuckKeys(stuck)
+}
+
+// forceRelease releases the keys of stuck processing message m, canceling its work context, and
+// promotes messages waiting for those keys.  The end message of the worker is answered with
+// ErrWorkStuck (see processEnd).
+func (s *Supervisor[K]) forceRelease(m message[K]) {
+	s.logger.Warn("Supervisor releasing keys of stuck work", []logging.LogTuple{
+		{Field: "key", Value: m.keyset().key},
+	})
+	m.signature().release()
+	s.purgeMessage(m)
+}
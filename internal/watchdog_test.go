@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/btsomogyi/arbiter/interfaces"
+	at "github.com/btsomogyi/arbiter/telemetry"
+)
+
+// timedReq is a testReq with its own maximum work duration.
+type timedReq struct {
+	testReq
+	timeout time.Duration
+}
+
+func (p *timedReq) WorkTimeout() time.Duration {
+	return p.timeout
+}
+
+func Test_SupervisorWatchdog(t *testing.T) {
+	const soon, never = 20 * time.Millisecond, time.Hour
+	tests := map[string]struct {
+		opts       []SupervisorOption
+		timeout    time.Duration // work timeout of the request, if set
+		honorCtx   bool          // work returns once its context ends, rather than ignoring it
+		watchdog   bool          // watchdog reports stuck work to a callback
+		stuck      int64         // stuck keys gauge while the work runs
+		waiter     bool          // a superseding request for the key must run while the work is stuck
+		wantErr    error
+		wantCtxErr error
+	}{
+		"max work duration ends context": {
+			opts:       []SupervisorOption{SetMaxWorkDuration(soon)},
+			honorCtx:   true,
+			wantErr:    ErrWorkFailed,
+			wantCtxErr: context.DeadlineExceeded,
+		},
+		"request work timeout overrides max": {
+			opts:       []SupervisorOption{SetMaxWorkDuration(never)},
+			timeout:    soon,
+			honorCtx:   true,
+			wantErr:    ErrWorkFailed,
+			wantCtxErr: context.DeadlineExceeded,
+		},
+		"watchdog reports stuck work": {
+			watchdog: true,
+			stuck:    1,
+		},
+		"watchdog releases stuck work": {
+			opts:     []SupervisorOption{SetWatchdogRelease(true)},
+			watchdog: true,
+			waiter:   true,
+			wantErr:  ErrWorkStuck,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var mtx sync.Mutex
+			var reported []int64
+			opts := tc.opts
+			if tc.watchdog {
+				opts = append(opts, SetWatchdog(soon, func(key int64, running time.Duration) {
+					mtx.Lock()
+					reported = append(reported, key)
+					mtx.Unlock()
+				}))
+			}
+			arbiter, db, ctx, _, li, err := testSetup(opts...)
+			if err != nil {
+				t.Fatalf("failed to setup test: %e", err)
+			}
+			go arbiter.Process()
+			defer arbiter.Terminate()
+
+			var r interfaces.Request[int64] = &timedReq{testReq: testReq{key: 1, value: 10}, timeout: tc.timeout}
+			setupTestItem(&r.(*timedReq).testReq, db)
+			unblock := make(chan struct{})
+			var ctxErr error
+			ticket := arbiter.Submit(ctx, r, func(ctx context.Context) error {
+				if tc.honorCtx {
+					<-ctx.Done()
+					ctxErr = ctx.Err()
+					return ctxErr
+				}
+				<-unblock
+				return nil
+			})
+			if tc.stuck > 0 {
+				waitForGauge(t, li, at.StuckKeys, tc.stuck)
+			}
+			if tc.waiter {
+				waiter := &timedReq{testReq: testReq{key: 1, value: 11}}
+				setupTestItem(&waiter.testReq, db)
+				wt := arbiter.Submit(ctx, waiter, func(context.Context) error { return nil })
+				select {
+				case <-wt.Done():
+				case <-time.After(time.Second):
+					t.Fatalf("expected waiting request to complete while work is stuck")
+				}
+				if err := wt.Result(); err != nil {
+					t.Errorf("expected waiting request to succeed, got: %v", err)
+				}
+			}
+			close(unblock)
+
+			<-ticket.Done()
+			if got := ticket.Result(); !errors.Is(got, tc.wantErr) || (tc.wantErr == nil && got != nil) {
+				t.Errorf("expected error %v, got: %v", tc.wantErr, got)
+			}
+			if ctxErr != tc.wantCtxErr {
+				t.Errorf("expected work context error %v, got: %v", tc.wantCtxErr, ctxErr)
+			}
+			waitForGauge(t, li, at.ProcessingMapDepth, 0)
+			if tc.watchdog {
+				waitForGauge(t, li, at.StuckKeys, 0)
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			if tc.watchdog && (len(reported) != 1 || reported[0] != 1) {
+				t.Errorf("expected stuck work reported once for key 1, got %v", reported)
+			}
+			if !tc.watchdog && len(reported) != 0 {
+				t.Errorf("expected no stuck work reported, got %v", reported)
+			}
+		})
+	}
+}
@@ -3,28 +3,108 @@ package internal
 import (
 	"context"
 	"github.com/btsomogyi/arbiter/interfaces"
+	"sync/atomic"
 	"time"
 
 	"github.com/btsomogyi/arbiter/telemetry"
 )
 
 // worker contains the supervisor reference and status values used to properly close channels.
-type worker struct {
-	queue     chan<- message
+type worker[K comparable] struct {
+	queue     chan<- message[K]
+	stopped   <-chan struct{}
 	metrics   telemetry.Instrumentor
 	response  chan response
 	done      chan struct{}
 	ctx       context.Context
+	workCtx   context.Context
 	status    signal
 	beginSent time.Time
 	endSent   time.Time
 	workStart time.Time
-	request   interfaces.Request
-	signature *worker
+	request   interfaces.Request[K]
+	signature *worker[K]
+	// key is the key of the request, for logging by the worker.
+	key K
+	// keys are the keys of the current request, and are only accessed by the supervisor once the
+	// begin message is sent.
+	keys *keyset[K]
+	// keyErr is the panic recovered resolving the keys of the request, in which case the begin
+	// message is not sent.
+	keyErr error
+	// cancelWork cancels the context passed to the work function, and is only set when
+	// preemption or watchdog release is enabled.
+	cancelWork context.CancelFunc
+	preempted  atomic.Bool
+	// released is set once the watchdog releases the key of the worker (see SetWatchdogRelease).
+	released atomic.Bool
+	// cancelDeadline releases the work context bounded by the maximum work duration, and is only
+	// accessed by the worker.
+	cancelDeadline context.CancelFunc
+	// shedDepth is the queue channel depth at which begin messages are shed (zero disables).
+	shedDepth int
+	// followers are the begin messages following the request of the worker (see SetFollowMode),
+	// and are only accessed by the supervisor.
+	followers []message[K]
+	// merged is the request replacing the request of the worker once merged with a later
+	// request (see interfaces.Merger), and is only accessed by the supervisor.
+	merged interfaces.Request[K]
+	// lease is set for workers of Acquire, which never follow or merge as the caller performs
+	// the work.
+	lease bool
 }
 
-func (w *worker) deferredFunc() {
+// current returns the request of the worker, or the merged request replacing it.
+func (w *worker[K]) current() interfaces.Request[K] {
+	if w.merged != nil {
+		return w.merged
+	}
+	return w.request
+}
+
+// preempt flags the worker as preempted and cancels its work context.  It is invoked by the
+// supervisor when a superseding request arrives for the in-flight request.
+func (w *worker[K]) preempt() {
+	w.preempted.Store(true)
+	if w.cancelWork != nil {
+		w.cancelWork()
+	}
+}
+
+// release flags the worker as released by the watchdog and cancels its work context.  It is
+// invoked by the supervisor when the work of the worker is stuck.
+func (w *worker[K]) release() {
+	w.released.Store(true)
+	if w.cancelWork != nil {
+		w.cancelWork()
+	}
+}
+
+// boundWork bounds the work context to the maximum work duration d, if positive, once the work
+// begins.
+func (w *worker[K]) boundWork(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	w.workCtx, w.cancelDeadline = context.WithTimeout(w.workContext(), d)
+}
+
+// workContext returns the context passed to the work function, which is canceled on preemption.
+func (w *worker[K]) workContext() context.Context {
+	if w.workCtx != nil {
+		return w.workCtx
+	}
+	return w.ctx
+}
+
+func (w *worker[K]) deferredFunc() {
 	close(w.done)
+	if w.cancelWork != nil {
+		defer w.cancelWork()
+	}
+	if w.cancelDeadline != nil {
+		defer w.cancelDeadline()
+	}
 	// Only send end if one has not already been sent.
 	if w.endSent.IsZero() {
 		// worker sends whatever status is stored in worker "status" field.
@@ -32,12 +112,21 @@ func (w *worker) deferredFunc() {
 	}
 }
 
-func (w *worker) responseToWorkerFunc(t state, s signal, e error) {
-	response := response{
-		state: t,
-		sig:   s,
-		err:   e,
+func (w *worker[K]) responseToWorkerFunc(t state, s signal, e error, ms messageStatus) {
+	// Followers are released with the final response, even if the worker has ceased.
+	if len(w.followers) > 0 && (t == endState || s != proceedSignal) {
+		w.releaseFollowers(s, e)
 	}
+	w.send(response{
+		state:  t,
+		sig:    s,
+		err:    e,
+		status: ms,
+	})
+}
+
+// send delivers the response to the worker, unless the worker has ceased.
+func (w *worker[K]) send(response response) {
 	select {
 	case <-w.done:
 		// client has ceased, abort any attempt to return messages.
@@ -46,51 +135,115 @@ func (w *worker) responseToWorkerFunc(t state, s signal, e error) {
 	}
 }
 
-func (w *worker) sendBegin() {
-	msg := beginMessage{
+// sendBegin sends the begin message to the supervisor, blocking while the queue channel is full
+// until it is sent or the context ends.  If try is set, sendBegin returns ErrQueueFull rather than blocking on a full queue
+// channel.  Begin messages are shed with ErrLoadShed if the queue channel depth has reached the
+// shedding depth.  No end message is sent for a worker whose begin message was not sent.
+func (w *worker[K]) sendBegin(try bool) error {
+	if w.keyErr != nil {
+		return w.abortBegin(wrapOutcome(ErrInvalid, w.keyErr))
+	}
+	msg := beginMessage[K]{
 		req:          w.request,
 		responseFunc: w.responseToWorkerFunc,
 		timestamp:    time.Now(),
 		workerSig:    w.signature,
 	}
 	w.beginSent = time.Now()
-	w.metrics.IncQueueChanDepth()
-	w.queue <- &msg
+	if w.shedDepth > 0 && len(w.queue) >= w.shedDepth {
+		w.metrics.Shed(telemetry.Labels{
+			"policy": "depth",
+		})
+		return w.abortBegin(ErrLoadShed)
+	}
+
+	// Send without blocking where possible, so a begin message is never dropped for a canceled
+	// context while the queue channel has room.
+	select {
+	case w.queue <- &msg:
+		w.metrics.IncQueueChanDepth()
+		return nil
+	default:
+	}
+	if try {
+		return w.abortBegin(ErrQueueFull)
+	}
+	select {
+	case w.queue <- &msg:
+		w.metrics.IncQueueChanDepth()
+	case <-w.stopped:
+		// Supervisor has stopped, recvResponse will respond with ErrShutdown.
+	case <-w.ctx.Done():
+		return w.abortBegin(wrapOutcome(ErrCanceled, w.ctx.Err()))
+	}
+	return nil
+}
+
+// abortBegin marks the end message as sent, since the supervisor never received the begin
+// message, and returns err.
+func (w *worker[K]) abortBegin(err error) error {
+	w.endSent = time.Now()
+	return err
 }
 
 // sendEnd creates an EndMessage from the signal embedded in worker and sends it to supervisor.
-func (w *worker) sendEnd() {
-	msg := endMessage{
+func (w *worker[K]) sendEnd() {
+	msg := endMessage[K]{
 		req:          w.request,
 		signal:       w.status,
 		responseFunc: w.responseToWorkerFunc,
 		timestamp:    time.Now(),
 		workerSig:    w.signature,
 	}
-	w.queue <- &msg
-	w.metrics.IncQueueChanDepth()
+	select {
+	case w.queue <- &msg:
+		w.metrics.IncQueueChanDepth()
+	case <-w.stopped:
+		// Supervisor has stopped, so there is no processing entry to release.
+	}
 	w.endSent = time.Now()
 }
 
 // recvResponse blocks returning the response from the Supervisor to the worker.
 // If the context is canceled prior to Supervisor response, respond to worker with
-func (w *worker) recvResponse(state state, defaultSig signal) response {
+// the default signal and context error.  If the Supervisor has stopped, respond to
+// worker with the default signal and ErrShutdown.
+func (w *worker[K]) recvResponse(state state, defaultSig signal) response {
 	select {
 	case resp := <-w.response:
 		return resp
+	case <-w.stopped:
+		// Any response sent prior to the Supervisor stopping takes precedence.
+		select {
+		case resp := <-w.response:
+			return resp
+		default:
+		}
+		return response{
+			state: state,
+			sig:   defaultSig,
+			err:   ErrShutdown,
+		}
 	case <-w.ctx.Done():
+		// Any response sent prior to the context ending takes precedence, as the worker may
+		// not have been receiving when it was sent.
+		select {
+		case resp := <-w.response:
+			return resp
+		default:
+		}
 		return response{
 			state: state,
 			sig:   defaultSig,
-			err:   w.ctx.Err(),
+			err:   wrapOutcome(ErrCanceled, w.ctx.Err()),
 		}
 	}
 }
 
-func (w *worker) duration() float64 {
+func (w *worker[K]) duration() float64 {
 	return timeElapsedInSeconds(w.beginSent)
 }
 
-func (w *worker) workDuration() float64 {
+func (w *worker[K]) workDuration() float64 {
 	return timeElapsedInSeconds(w.workStart)
 }
@@ -0,0 +1,121 @@
+package telemetry
+
+// LabeledInstrumentor wraps an Instrumentor, adding a fixed set of labels to every metric
+// reported (such as the shard of a sharded Supervisor).
+type LabeledInstrumentor struct {
+	instrumentor Instrumentor
+	labels       Labels
+}
+
+// LabeledInstrumentor implements the Instrumentor interface by wrapping another Instrumentor.
+var _ Instrumentor = (*LabeledInstrumentor)(nil)
+
+// NewLabeledInstrumentor returns an Instrumentor which reports all metrics to i with the
+// provided labels added.
+func NewLabeledInstrumentor(i Instrumentor, labels Labels) *LabeledInstrumentor {
+	return &LabeledInstrumentor{
+		instrumentor: i,
+		labels:       labels,
+	}
+}
+
+func (li *LabeledInstrumentor) QueueChanDepth(value int64, labels ...Labels) {
+	li.instrumentor.QueueChanDepth(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) IncQueueChanDepth(labels ...Labels) {
+	li.instrumentor.IncQueueChanDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) DecQueueChanDepth(labels ...Labels) {
+	li.instrumentor.DecQueueChanDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) ProcessingMapDepth(value int64, labels ...Labels) {
+	li.instrumentor.ProcessingMapDepth(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) IncProcessingMapDepth(labels ...Labels) {
+	li.instrumentor.IncProcessingMapDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) DecProcessingMapDepth(labels ...Labels) {
+	li.instrumentor.DecProcessingMapDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) WaitingMapDepth(value int64, labels ...Labels) {
+	li.instrumentor.WaitingMapDepth(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) IncWaitingMapDepth(labels ...Labels) {
+	li.instrumentor.IncWaitingMapDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) DecWaitingMapDepth(labels ...Labels) {
+	li.instrumentor.DecWaitingMapDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) AdmissionQueueDepth(value int64, labels ...Labels) {
+	li.instrumentor.AdmissionQueueDepth(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) IncAdmissionQueueDepth(labels ...Labels) {
+	li.instrumentor.IncAdmissionQueueDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) DecAdmissionQueueDepth(labels ...Labels) {
+	li.instrumentor.DecAdmissionQueueDepth(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) RateLimitTokens(value int64, labels ...Labels) {
+	li.instrumentor.RateLimitTokens(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) StuckKeys(value int64, labels ...Labels) {
+	li.instrumentor.StuckKeys(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Messages(value float64, labels ...Labels) {
+	li.instrumentor.Messages(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Worktime(value float64, labels ...Labels) {
+	li.instrumentor.Worktime(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Transactions(value float64, labels ...Labels) {
+	li.instrumentor.Transactions(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) AdmissionWait(value float64, labels ...Labels) {
+	li.instrumentor.AdmissionWait(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) DependencyWait(value float64, labels ...Labels) {
+	li.instrumentor.DependencyWait(value, li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Panics(labels ...Labels) {
+	li.instrumentor.Panics(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Shed(labels ...Labels) {
+	li.instrumentor.Shed(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) RateLimited(labels ...Labels) {
+	li.instrumentor.RateLimited(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Coalesced(labels ...Labels) {
+	li.instrumentor.Coalesced(li.with(labels)...)
+}
+
+func (li *LabeledInstrumentor) Expired(labels ...Labels) {
+	li.instrumentor.Expired(li.with(labels)...)
+}
+
+// with returns the provided labels preceded by the fixed labels of the LabeledInstrumentor.
+func (li *LabeledInstrumentor) with(labels []Labels) []Labels {
+	return append([]Labels{li.labels}, labels...)
+}
@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labelRecorder records the labels passed to gauge increments.
+type labelRecorder struct {
+	NopInstrumentor
+	labels []Labels
+}
+
+func (lr *labelRecorder) IncWaitingMapDepth(labels ...Labels) {
+	lr.labels = labels
+}
+
+func Test_LabeledInstrumentor(t *testing.T) {
+	lr := &labelRecorder{}
+	li := NewLabeledInstrumentor(lr, Labels{"shard": "3"})
+	li.IncWaitingMapDepth(Labels{"other": "value"})
+
+	want := []Labels{{"shard": "3"}, {"other": "value"}}
+	if diff := cmp.Diff(want, lr.labels); diff != "" {
+		t.Errorf("labels mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func Test_AggLabels(t *testing.T) {
+	tests := map[string]struct {
+		names  []string
+		labels []Labels
+		want   prometheus.Labels
+	}{
+		"no labels": {
+			names: []string{"shard"},
+			want:  prometheus.Labels{"shard": ""},
+		},
+		"matching labels": {
+			names:  []string{"shard", "signal"},
+			labels: []Labels{{"shard": "1"}, {"signal": "success"}},
+			want:   prometheus.Labels{"shard": "1", "signal": "success"},
+		},
+		"unknown labels dropped": {
+			names:  []string{"signal"},
+			labels: []Labels{{"shard": "1", "signal": "failure"}},
+			want:   prometheus.Labels{"signal": "failure"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, aggLabels(tc.names, tc.labels...)); diff != "" {
+				t.Errorf("labels mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ShardLabels(t *testing.T) {
+	for k, names := range MetricHistogramLabels {
+		if got := aggLabels(names, Labels{"shard": "2"})["shard"]; got != "2" {
+			t.Errorf("histogram %s: expected shard label 2, got %q", k, got)
+		}
+	}
+	for k, names := range MetricGaugeLabels {
+		if got := aggLabels(names, Labels{"shard": "2"})["shard"]; got != "2" {
+			t.Errorf("gauge %s: expected shard label 2, got %q", k, got)
+		}
+	}
+	for k, names := range MetricCounterLabels {
+		if got := aggLabels(names, Labels{"shard": "2"})["shard"]; got != "2" {
+			t.Errorf("counter %s: expected shard label 2, got %q", k, got)
+		}
+	}
+}
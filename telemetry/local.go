@@ -14,6 +14,7 @@ var localBuckets = []float64{
 type LocalInstrumentor struct {
 	gauges     map[MetricGauge]int64
 	histograms map[MetricHistogram]map[float64]int64
+	counters   map[MetricCounter]int64
 	atomic     sync.Mutex
 }
 
@@ -27,6 +28,7 @@ func NewLocalInstrumentor() *LocalInstrumentor {
 	li := LocalInstrumentor{
 		gauges:     g,
 		histograms: h,
+		counters:   make(map[MetricCounter]int64),
 	}
 	for name := range MetricGauges {
 		li.gauges[name] = 0
@@ -45,42 +47,62 @@ type supervisorMetrics struct {
 	atomic     sync.Mutex
 }
 
-func (li *LocalInstrumentor) QueueChanDepth(value int64) {
+func (li *LocalInstrumentor) QueueChanDepth(value int64, _ ...Labels) {
 	li.setGauge(QueueChanDepth, value)
 }
 
-func (li *LocalInstrumentor) IncQueueChanDepth() {
+func (li *LocalInstrumentor) IncQueueChanDepth(_ ...Labels) {
 	li.incGauge(QueueChanDepth)
 }
 
-func (li *LocalInstrumentor) DecQueueChanDepth() {
+func (li *LocalInstrumentor) DecQueueChanDepth(_ ...Labels) {
 	li.decGauge(QueueChanDepth)
 }
 
-func (li *LocalInstrumentor) ProcessingMapDepth(value int64) {
+func (li *LocalInstrumentor) ProcessingMapDepth(value int64, _ ...Labels) {
 	li.setGauge(ProcessingMapDepth, value)
 }
 
-func (li *LocalInstrumentor) IncProcessingMapDepth() {
+func (li *LocalInstrumentor) IncProcessingMapDepth(_ ...Labels) {
 	li.incGauge(ProcessingMapDepth)
 }
 
-func (li *LocalInstrumentor) DecProcessingMapDepth() {
+func (li *LocalInstrumentor) DecProcessingMapDepth(_ ...Labels) {
 	li.decGauge(ProcessingMapDepth)
 }
 
-func (li *LocalInstrumentor) WaitingMapDepth(value int64) {
+func (li *LocalInstrumentor) WaitingMapDepth(value int64, _ ...Labels) {
 	li.setGauge(WaitingMapDepth, value)
 }
 
-func (li *LocalInstrumentor) IncWaitingMapDepth() {
+func (li *LocalInstrumentor) IncWaitingMapDepth(_ ...Labels) {
 	li.incGauge(WaitingMapDepth)
 }
 
-func (li *LocalInstrumentor) DecWaitingMapDepth() {
+func (li *LocalInstrumentor) DecWaitingMapDepth(_ ...Labels) {
 	li.decGauge(WaitingMapDepth)
 }
 
+func (li *LocalInstrumentor) AdmissionQueueDepth(value int64, _ ...Labels) {
+	li.setGauge(AdmissionQueueDepth, value)
+}
+
+func (li *LocalInstrumentor) IncAdmissionQueueDepth(_ ...Labels) {
+	li.incGauge(AdmissionQueueDepth)
+}
+
+func (li *LocalInstrumentor) DecAdmissionQueueDepth(_ ...Labels) {
+	li.decGauge(AdmissionQueueDepth)
+}
+
+func (li *LocalInstrumentor) RateLimitTokens(value int64, _ ...Labels) {
+	li.setGauge(RateLimitTokens, value)
+}
+
+func (li *LocalInstrumentor) StuckKeys(value int64, _ ...Labels) {
+	li.setGauge(StuckKeys, value)
+}
+
 func (li *LocalInstrumentor) Messages(value float64, _ ...Labels) {
 	li.addHistogramEntry(Messages, value)
 }
@@ -93,6 +115,34 @@ func (li *LocalInstrumentor) Transactions(value float64, _ ...Labels) {
 	li.addHistogramEntry(Transactions, value)
 }
 
+func (li *LocalInstrumentor) AdmissionWait(value float64, _ ...Labels) {
+	li.addHistogramEntry(AdmissionWait, value)
+}
+
+func (li *LocalInstrumentor) DependencyWait(value float64, _ ...Labels) {
+	li.addHistogramEntry(DependencyWait, value)
+}
+
+func (li *LocalInstrumentor) Panics(_ ...Labels) {
+	li.incCounter(Panics)
+}
+
+func (li *LocalInstrumentor) Shed(_ ...Labels) {
+	li.incCounter(Shed)
+}
+
+func (li *LocalInstrumentor) RateLimited(_ ...Labels) {
+	li.incCounter(RateLimited)
+}
+
+func (li *LocalInstrumentor) Coalesced(_ ...Labels) {
+	li.incCounter(Coalesced)
+}
+
+func (li *LocalInstrumentor) Expired(_ ...Labels) {
+	li.incCounter(Expired)
+}
+
 // setGauge sets the parameter metric.
 func (li *LocalInstrumentor) setGauge(m MetricGauge, value int64) {
 	li.atomic.Lock()
@@ -114,6 +164,16 @@ func (li *LocalInstrumentor) decGauge(m MetricGauge) {
 	li.atomic.Unlock()
 }
 
+// incCounter increments the parameter metric.
+func (li *LocalInstrumentor) incCounter(m MetricCounter) {
+	li.atomic.Lock()
+	if li.counters == nil {
+		li.counters = make(map[MetricCounter]int64)
+	}
+	li.counters[m]++
+	li.atomic.Unlock()
+}
+
 // addHistogramEntry adds an entry to parameter metric.
 func (li *LocalInstrumentor) addHistogramEntry(m MetricHistogram, value float64) {
 	li.atomic.Lock()
@@ -142,6 +202,7 @@ func (li *LocalInstrumentor) addHistogramEntry(m MetricHistogram, value float64)
 type MetricSnap struct {
 	Gauges     map[MetricGauge]int64
 	Histograms map[MetricHistogram]map[float64]int64
+	Counters   map[MetricCounter]int64 // nil unless a counter has been incremented.
 }
 
 // HistogramSummaries rolls up each histogram to a summary of the number of entries in each.
@@ -168,6 +229,12 @@ func (li *LocalInstrumentor) SnapMetrics() MetricSnap {
 	for mg, mval := range li.gauges {
 		snap.Gauges[mg] = mval
 	}
+	for mc, mval := range li.counters {
+		if snap.Counters == nil {
+			snap.Counters = make(map[MetricCounter]int64)
+		}
+		snap.Counters[mc] = mval
+	}
 	for mh, hmap := range li.histograms {
 		if len(hmap) > 0 {
 			snap.Histograms[mh] = make(map[float64]int64)
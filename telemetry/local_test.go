@@ -209,6 +209,20 @@ func Test_LocalInstrumentor(t *testing.T) {
 				Histograms: emptyHistograms,
 			},
 		},
+		"counter": {
+			metricOps: func(li *LocalInstrumentor) *LocalInstrumentor {
+				li.Panics()
+				li.Panics(Labels{"callback": "Valid"})
+				return li
+			},
+			want: MetricSnap{
+				Gauges:     map[MetricGauge]int64{},
+				Histograms: emptyHistograms,
+				Counters: map[MetricCounter]int64{
+					Panics: 2,
+				},
+			},
+		},
 		"single histogram": {
 			metricOps: func(li *LocalInstrumentor) *LocalInstrumentor {
 				li.Transactions(5.1)
@@ -344,6 +358,12 @@ func Test_LocalInstrumentor(t *testing.T) {
 			li := NewLocalInstrumentor()
 
 			tc.metricOps(li)
+			// Gauges not listed in the test case are expected to remain zero.
+			for gauge := range MetricGauges {
+				if _, ok := tc.want.Gauges[gauge]; !ok {
+					tc.want.Gauges[gauge] = 0
+				}
+			}
 
 			got := li.SnapMetrics()
 			diff := cmp.Diff(tc.want, got)
@@ -2,18 +2,30 @@ package telemetry
 
 // Instrumentor is the interface to be implemented by any concrete telemetry type.
 type Instrumentor interface {
-	QueueChanDepth(int64)
-	IncQueueChanDepth()
-	DecQueueChanDepth()
-	ProcessingMapDepth(int64)
-	IncProcessingMapDepth()
-	DecProcessingMapDepth()
-	WaitingMapDepth(int64)
-	IncWaitingMapDepth()
-	DecWaitingMapDepth()
+	QueueChanDepth(int64, ...Labels)
+	IncQueueChanDepth(...Labels)
+	DecQueueChanDepth(...Labels)
+	ProcessingMapDepth(int64, ...Labels)
+	IncProcessingMapDepth(...Labels)
+	DecProcessingMapDepth(...Labels)
+	WaitingMapDepth(int64, ...Labels)
+	IncWaitingMapDepth(...Labels)
+	DecWaitingMapDepth(...Labels)
+	AdmissionQueueDepth(int64, ...Labels)
+	IncAdmissionQueueDepth(...Labels)
+	DecAdmissionQueueDepth(...Labels)
+	RateLimitTokens(int64, ...Labels)
+	StuckKeys(int64, ...Labels)
 	Messages(float64, ...Labels)
 	Worktime(float64, ...Labels)
 	Transactions(float64, ...Labels)
+	AdmissionWait(float64, ...Labels)
+	DependencyWait(float64, ...Labels)
+	Panics(...Labels)
+	Shed(...Labels)
+	RateLimited(...Labels)
+	Coalesced(...Labels)
+	Expired(...Labels)
 }
 
 // Labels are used to signify dimensions of the stored metrics (states/results/statuses).
@@ -25,18 +37,35 @@ type MetricGauge int
 // MetricHistogram is the enum used to index the Histogram map.
 type MetricHistogram int
 
+// MetricCounter is the enum used to index the Counter map.
+type MetricCounter int
+
 // MetricGauge index constants
 const (
-	QueueChanDepth     MetricGauge = iota // point in time number of entries in begin channel.
-	ProcessingMapDepth                    // point in time number of entries in the processing map.
-	WaitingMapDepth                       // point in time number of entries in the waiting map.
+	QueueChanDepth      MetricGauge = iota // point in time number of entries in begin channel.
+	ProcessingMapDepth                     // point in time number of entries in the processing map.
+	WaitingMapDepth                        // point in time number of entries in the waiting map.
+	AdmissionQueueDepth                    // point in time number of entries awaiting a processing slot.
+	RateLimitTokens                        // point in time number of tokens in the global rate limit bucket.
+	StuckKeys                              // point in time number of processing entries past the watchdog threshold.
 )
 
 // MetricHistogram index constants
 const (
-	Messages     MetricHistogram = iota // time between send of Begin message and it being processed.
-	Worktime                            // time between send of End message and it being processed.
-	Transactions                        // time between send of begin of transaction and completion.
+	Messages       MetricHistogram = iota // time between send of Begin message and it being processed.
+	Worktime                              // time between send of End message and it being processed.
+	Transactions                          // time between send of begin of transaction and completion.
+	AdmissionWait                         // time between admission queueing and receipt of a processing slot.
+	DependencyWait                        // time requests wait for the keys they depend on to complete.
+)
+
+// MetricCounter index constants
+const (
+	Panics      MetricCounter = iota // number of panics recovered from request methods and work functions.
+	Shed                             // number of begin messages shed under load.
+	RateLimited                      // number of requests delayed or ceased by rate limits.
+	Coalesced                        // number of requests merged into a waiting request.
+	Expired                          // number of waiting requests ceased once their expiry passed.
 )
 
 func (m MetricGauge) String() string {
@@ -44,6 +73,9 @@ func (m MetricGauge) String() string {
 		"QueueChanDepth",
 		"ProcessingMapDepth",
 		"WaitingMapDepth",
+		"AdmissionQueueDepth",
+		"RateLimitTokens",
+		"StuckKeys",
 	}[m]
 }
 
@@ -52,35 +84,102 @@ func (m MetricHistogram) String() string {
 		"Messages",
 		"Worktime",
 		"Transaction",
+		"AdmissionWait",
+		"DependencyWait",
+	}[m]
+}
+
+func (m MetricCounter) String() string {
+	return [...]string{
+		"Panics",
+		"Shed",
+		"RateLimited",
+		"Coalesced",
+		"Expired",
 	}[m]
 }
 
 // MetricGauges is the collection of Gauge metrics implemented by package.
 var MetricGauges = map[MetricGauge]string{
-	QueueChanDepth:     "Number of messages in queue channel",
-	ProcessingMapDepth: "Number of active processing messages",
-	WaitingMapDepth:    "Number of waiting messages",
+	QueueChanDepth:      "Number of messages in queue channel",
+	ProcessingMapDepth:  "Number of active processing messages",
+	WaitingMapDepth:     "Number of waiting messages",
+	AdmissionQueueDepth: "Number of messages awaiting a processing slot",
+	RateLimitTokens:     "Number of tokens available in the global rate limit bucket",
+	StuckKeys:           "Number of processing entries whose work has run past the watchdog threshold",
 }
 
 // MetricHistograms is the collection of Histogram metrics implemented by package.
 var MetricHistograms = map[MetricHistogram]string{
-	Messages:     "Time before supervisor processes messages sent from worker",
-	Worktime:     "Time it takes to complete the work being arbitrated (passed in closure function)",
-	Transactions: "Total time between begin of transaction and completion",
+	Messages:       "Time before supervisor processes messages sent from worker",
+	Worktime:       "Time it takes to complete the work being arbitrated (passed in closure function)",
+	Transactions:   "Total time between begin of transaction and completion",
+	AdmissionWait:  "Time messages wait in the admission queue for a processing slot",
+	DependencyWait: "Time requests wait for the keys they depend on to complete",
+}
+
+// MetricCounters is the collection of Counter metrics implemented by package.
+var MetricCounters = map[MetricCounter]string{
+	Panics:      "Number of panics recovered from request methods and work functions",
+	Shed:        "Number of requests shed by the load shedding policy",
+	RateLimited: "Number of requests delayed or ceased by rate limits",
+	Coalesced:   "Number of requests merged into a waiting request",
+	Expired:     "Number of waiting requests ceased once their expiry passed",
 }
 
-// MetricHistogramLabels provides the label keys for Histogram Vectors in Prometheus.
+// MetricGaugeLabels provides the label keys for Gauge Vectors in Prometheus.  Gauges reported
+// without labels (such as from an unsharded Supervisor) use empty label values.
+var MetricGaugeLabels = map[MetricGauge][]string{
+	QueueChanDepth:      {"shard"},
+	ProcessingMapDepth:  {"shard"},
+	WaitingMapDepth:     {"shard"},
+	AdmissionQueueDepth: {"shard"},
+	RateLimitTokens:     {"shard"},
+	StuckKeys:           {"shard"},
+}
+
+// MetricHistogramLabels provides the label keys for Histogram Vectors in Prometheus.  Histograms
+// reported without a shard (such as from an unsharded Supervisor) use an empty shard label value.
 var MetricHistogramLabels = map[MetricHistogram][]string{
 	Messages: {
 		"state",
 		"signal",
 		"waitlisted",
 		"finalizefailed",
+		"priority",
+		"shard",
 	},
 	Worktime: {
 		"signal",
+		"shard",
 	},
 	Transactions: {
 		"signal",
+		"shard",
+	},
+	AdmissionWait:  {"shard"},
+	DependencyWait: {"shard"},
+}
+
+// MetricCounterLabels provides the label keys for Counter Vectors in Prometheus.
+var MetricCounterLabels = map[MetricCounter][]string{
+	Panics: {
+		"callback",
+		"shard",
+	},
+	Shed: {
+		"policy",
+		"shard",
+	},
+	RateLimited: {
+		"policy",
+		"scope",
+		"shard",
+	},
+	Coalesced: {
+		"shard",
+	},
+	Expired: {
+		"shard",
 	},
 }
@@ -11,31 +11,46 @@ func NewNopInstrumentor() NopInstrumentor {
 	return NopInstrumentor{}
 }
 
-func (ni NopInstrumentor) QueueChanDepth(_ int64) {
+func (ni NopInstrumentor) QueueChanDepth(_ int64, _ ...Labels) {
 }
 
-func (ni NopInstrumentor) IncQueueChanDepth() {
+func (ni NopInstrumentor) IncQueueChanDepth(_ ...Labels) {
 }
 
-func (ni NopInstrumentor) DecQueueChanDepth() {
+func (ni NopInstrumentor) DecQueueChanDepth(_ ...Labels) {
 }
 
-func (ni NopInstrumentor) ProcessingMapDepth(_ int64) {
+func (ni NopInstrumentor) ProcessingMapDepth(_ int64, _ ...Labels) {
 }
 
-func (ni NopInstrumentor) IncProcessingMapDepth() {
+func (ni NopInstrumentor) IncProcessingMapDepth(_ ...Labels) {
 }
 
-func (ni NopInstrumentor) DecProcessingMapDepth() {
+func (ni NopInstrumentor) DecProcessingMapDepth(_ ...Labels) {
 }
 
-func (ni NopInstrumentor) WaitingMapDepth(_ int64) {
+func (ni NopInstrumentor) WaitingMapDepth(_ int64, _ ...Labels) {
 }
 
-func (ni NopInstrumentor) IncWaitingMapDepth() {
+func (ni NopInstrumentor) IncWaitingMapDepth(_ ...Labels) {
 }
 
-func (ni NopInstrumentor) DecWaitingMapDepth() {
+func (ni NopInstrumentor) DecWaitingMapDepth(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) AdmissionQueueDepth(_ int64, _ ...Labels) {
+}
+
+func (ni NopInstrumentor) IncAdmissionQueueDepth(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) DecAdmissionQueueDepth(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) RateLimitTokens(_ int64, _ ...Labels) {
+}
+
+func (ni NopInstrumentor) StuckKeys(_ int64, _ ...Labels) {
 }
 
 func (ni NopInstrumentor) Messages(_ float64, _ ...Labels) {
@@ -46,3 +61,24 @@ func (ni NopInstrumentor) Worktime(_ float64, _ ...Labels) {
 
 func (ni NopInstrumentor) Transactions(_ float64, _ ...Labels) {
 }
+
+func (ni NopInstrumentor) AdmissionWait(_ float64, _ ...Labels) {
+}
+
+func (ni NopInstrumentor) DependencyWait(_ float64, _ ...Labels) {
+}
+
+func (ni NopInstrumentor) Panics(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) Shed(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) RateLimited(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) Coalesced(_ ...Labels) {
+}
+
+func (ni NopInstrumentor) Expired(_ ...Labels) {
+}
@@ -11,8 +11,9 @@ var promBuckets = prometheus.ExponentialBuckets(0.00002, 2, 20)
 
 // PromInstrumentor implements the arbiter Instrumentor interface using prometheus metrics package.
 type PromInstrumentor struct {
-	gaugeMetrics     map[MetricGauge]prometheus.Gauge
+	gaugeMetrics     map[MetricGauge]*prometheus.GaugeVec
 	histogramMetrics map[MetricHistogram]*prometheus.HistogramVec
+	counterMetrics   map[MetricCounter]*prometheus.CounterVec
 }
 
 // PromInstrumenter implements the Instrumentor interface using prometheus metrics.
@@ -22,15 +23,16 @@ var _ Instrumentor = (*PromInstrumentor)(nil)
 // InstrumentPromMetrics sets up arbiter metrics in the prometheus metrics node.
 func InstrumentPromMetrics() *PromInstrumentor {
 	pi := PromInstrumentor{
-		gaugeMetrics:     make(map[MetricGauge]prometheus.Gauge),
+		gaugeMetrics:     make(map[MetricGauge]*prometheus.GaugeVec),
 		histogramMetrics: make(map[MetricHistogram]*prometheus.HistogramVec),
+		counterMetrics:   make(map[MetricCounter]*prometheus.CounterVec),
 	}
 	for k, v := range MetricGauges {
-		pi.gaugeMetrics[k] = promauto.NewGauge(prometheus.GaugeOpts{
+		pi.gaugeMetrics[k] = promauto.NewGaugeVec(prometheus.GaugeOpts{
 			Subsystem: subsystemSpecifier,
 			Name:      k.String(),
 			Help:      v,
-		})
+		}, MetricGaugeLabels[k])
 	}
 
 	for k, v := range MetricHistograms {
@@ -42,62 +44,131 @@ func InstrumentPromMetrics() *PromInstrumentor {
 		}, MetricHistogramLabels[k])
 	}
 
+	for k, v := range MetricCounters {
+		pi.counterMetrics[k] = promauto.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystemSpecifier,
+			Name:      k.String(),
+			Help:      v,
+		}, MetricCounterLabels[k])
+	}
+
 	return &pi
 }
 
-func (pi *PromInstrumentor) QueueChanDepth(value int64) {
-	pi.gaugeMetrics[QueueChanDepth].Set(float64(value))
+func (pi *PromInstrumentor) QueueChanDepth(value int64, labels ...Labels) {
+	pi.gauge(QueueChanDepth, labels...).Set(float64(value))
+}
+
+func (pi *PromInstrumentor) IncQueueChanDepth(labels ...Labels) {
+	pi.gauge(QueueChanDepth, labels...).Inc()
+}
+
+func (pi *PromInstrumentor) DecQueueChanDepth(labels ...Labels) {
+	pi.gauge(QueueChanDepth, labels...).Dec()
+}
+
+func (pi *PromInstrumentor) ProcessingMapDepth(value int64, labels ...Labels) {
+	pi.gauge(ProcessingMapDepth, labels...).Set(float64(value))
+}
+
+func (pi *PromInstrumentor) IncProcessingMapDepth(labels ...Labels) {
+	pi.gauge(ProcessingMapDepth, labels...).Inc()
+}
+
+func (pi *PromInstrumentor) DecProcessingMapDepth(labels ...Labels) {
+	pi.gauge(ProcessingMapDepth, labels...).Dec()
 }
 
-func (pi *PromInstrumentor) IncQueueChanDepth() {
-	pi.gaugeMetrics[QueueChanDepth].Inc()
+func (pi *PromInstrumentor) WaitingMapDepth(value int64, labels ...Labels) {
+	pi.gauge(WaitingMapDepth, labels...).Set(float64(value))
 }
 
-func (pi *PromInstrumentor) DecQueueChanDepth() {
-	pi.gaugeMetrics[QueueChanDepth].Dec()
+func (pi *PromInstrumentor) IncWaitingMapDepth(labels ...Labels) {
+	pi.gauge(WaitingMapDepth, labels...).Inc()
 }
 
-func (pi *PromInstrumentor) ProcessingMapDepth(value int64) {
-	pi.gaugeMetrics[ProcessingMapDepth].Set(float64(value))
+func (pi *PromInstrumentor) DecWaitingMapDepth(labels ...Labels) {
+	pi.gauge(WaitingMapDepth, labels...).Dec()
 }
 
-func (pi *PromInstrumentor) IncProcessingMapDepth() {
-	pi.gaugeMetrics[ProcessingMapDepth].Inc()
+func (pi *PromInstrumentor) AdmissionQueueDepth(value int64, labels ...Labels) {
+	pi.gauge(AdmissionQueueDepth, labels...).Set(float64(value))
 }
 
-func (pi *PromInstrumentor) DecProcessingMapDepth() {
-	pi.gaugeMetrics[ProcessingMapDepth].Dec()
+func (pi *PromInstrumentor) IncAdmissionQueueDepth(labels ...Labels) {
+	pi.gauge(AdmissionQueueDepth, labels...).Inc()
 }
 
-func (pi *PromInstrumentor) WaitingMapDepth(value int64) {
-	pi.gaugeMetrics[WaitingMapDepth].Set(float64(value))
+func (pi *PromInstrumentor) DecAdmissionQueueDepth(labels ...Labels) {
+	pi.gauge(AdmissionQueueDepth, labels...).Dec()
 }
 
-func (pi *PromInstrumentor) IncWaitingMapDepth() {
-	pi.gaugeMetrics[WaitingMapDepth].Inc()
+func (pi *PromInstrumentor) RateLimitTokens(value int64, labels ...Labels) {
+	pi.gauge(RateLimitTokens, labels...).Set(float64(value))
 }
 
-func (pi *PromInstrumentor) DecWaitingMapDepth() {
-	pi.gaugeMetrics[WaitingMapDepth].Dec()
+func (pi *PromInstrumentor) StuckKeys(value int64, labels ...Labels) {
+	pi.gauge(StuckKeys, labels...).Set(float64(value))
 }
 
 func (pi *PromInstrumentor) Messages(value float64, labels ...Labels) {
-	pi.histogramMetrics[Messages].With(prometheus.Labels(aggLabels(labels...))).Observe(value)
+	pi.histogramMetrics[Messages].With(aggLabels(MetricHistogramLabels[Messages], labels...)).Observe(value)
 }
 
 func (pi *PromInstrumentor) Worktime(value float64, labels ...Labels) {
-	pi.histogramMetrics[Worktime].With(prometheus.Labels(aggLabels(labels...))).Observe(value)
+	pi.histogramMetrics[Worktime].With(aggLabels(MetricHistogramLabels[Worktime], labels...)).Observe(value)
 }
 
 func (pi *PromInstrumentor) Transactions(value float64, labels ...Labels) {
-	pi.histogramMetrics[Transactions].With(prometheus.Labels(aggLabels(labels...))).Observe(value)
+	pi.histogramMetrics[Transactions].With(aggLabels(MetricHistogramLabels[Transactions], labels...)).Observe(value)
+}
+
+func (pi *PromInstrumentor) AdmissionWait(value float64, labels ...Labels) {
+	pi.histogramMetrics[AdmissionWait].With(aggLabels(MetricHistogramLabels[AdmissionWait], labels...)).Observe(value)
+}
+
+func (pi *PromInstrumentor) DependencyWait(value float64, labels ...Labels) {
+	pi.histogramMetrics[DependencyWait].With(aggLabels(MetricHistogramLabels[DependencyWait], labels...)).Observe(value)
+}
+
+func (pi *PromInstrumentor) Panics(labels ...Labels) {
+	pi.counterMetrics[Panics].With(aggLabels(MetricCounterLabels[Panics], labels...)).Inc()
+}
+
+func (pi *PromInstrumentor) Shed(labels ...Labels) {
+	pi.counterMetrics[Shed].With(aggLabels(MetricCounterLabels[Shed], labels...)).Inc()
+}
+
+func (pi *PromInstrumentor) RateLimited(labels ...Labels) {
+	pi.counterMetrics[RateLimited].With(aggLabels(MetricCounterLabels[RateLimited], labels...)).Inc()
+}
+
+func (pi *PromInstrumentor) Coalesced(labels ...Labels) {
+	pi.counterMetrics[Coalesced].With(aggLabels(MetricCounterLabels[Coalesced], labels...)).Inc()
+}
+
+func (pi *PromInstrumentor) Expired(labels ...Labels) {
+	pi.counterMetrics[Expired].With(aggLabels(MetricCounterLabels[Expired], labels...)).Inc()
+}
+
+// gauge returns the Gauge from the GaugeVec for the metric, with the provided labels.
+func (pi *PromInstrumentor) gauge(m MetricGauge, labels ...Labels) prometheus.Gauge {
+	return pi.gaugeMetrics[m].With(aggLabels(MetricGaugeLabels[m], labels...))
 }
 
-func aggLabels(labels ...Labels) Labels {
-	var agg Labels
+// aggLabels aggregates the provided labels into the label set for a metric vector with the
+// provided label names. Names not provided are given empty values, and labels not among the
+// names are dropped, as prometheus requires exactly the label names of the vector.
+func aggLabels(names []string, labels ...Labels) prometheus.Labels {
+	agg := make(prometheus.Labels, len(names))
+	for _, name := range names {
+		agg[name] = ""
+	}
 	for _, l := range labels {
 		for k, v := range l {
-			agg[k] = v
+			if _, ok := agg[k]; ok {
+				agg[k] = v
+			}
 		}
 	}
 	return agg